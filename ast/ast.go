@@ -11,6 +11,8 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position // position of the first character of the node
+	End() token.Position // position immediately after the node
 }
 
 // Statement are sentences in program
@@ -38,6 +40,22 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+// Pos return position of first statement in program
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
+// End return end of last statement in program
+func (p *Program) End() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+	return token.Position{}
+}
+
 // String return string view of all statements
 func (p *Program) String() string {
 	var out bytes.Buffer
@@ -49,18 +67,35 @@ func (p *Program) String() string {
 	return out.String()
 }
 
-// LetStatement is for "let" statement
+// LetStatement is for "let" statement, or "export let" at the top level of a module
 type LetStatement struct {
-	Token token.Token // token.LET
-	Name  *Identifier
-	Value Expression
+	Token    token.Token // token.LET
+	Name     *Identifier
+	Value    Expression
+	Exported bool
+	Export   token.Position // position of "export", if Exported
 }
 
 // TokenLiteral return "let"
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() token.Position {
+	if ls.Exported {
+		return ls.Export
+	}
+	return ls.Token.Pos
+}
+func (ls *LetStatement) End() token.Position {
+	if ls.Value != nil {
+		return ls.Value.End()
+	}
+	return ls.Name.End()
+}
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
+	if ls.Exported {
+		out.WriteString("export ")
+	}
 	out.WriteString(ls.TokenLiteral() + " ")
 	out.WriteString(ls.Name.String())
 	out.WriteString(" = ")
@@ -83,6 +118,8 @@ type Identifier struct {
 
 // TokenLiteral return variable name(x, tmp, etc...)
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() token.Position  { return i.Token.Pos }
+func (i *Identifier) End() token.Position  { return i.Token.End }
 func (i *Identifier) String() string       { return i.Value }
 func (i *Identifier) expressionNode()      {}
 
@@ -94,6 +131,13 @@ type ReturnStatement struct {
 
 // TokenLiteral return "return"
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() token.Position  { return rs.Token.Pos }
+func (rs *ReturnStatement) End() token.Position {
+	if rs.ReturnValue != nil {
+		return rs.ReturnValue.End()
+	}
+	return rs.Token.End
+}
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 
@@ -117,6 +161,13 @@ type ExpressionStatement struct {
 
 // TokenLiteral return a token of first of expression
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() token.Position  { return es.Token.Pos }
+func (es *ExpressionStatement) End() token.Position {
+	if es.Expression != nil {
+		return es.Expression.End()
+	}
+	return es.Token.End
+}
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil { // HACK: nil check
 		return es.Expression.String()
@@ -133,9 +184,54 @@ type IntegerLiteral struct {
 
 // TokenLiteral return integer
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() token.Position  { return il.Token.Pos }
+func (il *IntegerLiteral) End() token.Position  { return il.Token.End }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 func (il *IntegerLiteral) expressionNode()      {}
 
+// FloatLiteral for floating-point literal
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+// TokenLiteral return float
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() token.Position  { return fl.Token.Pos }
+func (fl *FloatLiteral) End() token.Position  { return fl.Token.End }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+func (fl *FloatLiteral) expressionNode()      {}
+
+// AssignExpression for 'target = value' and compound assignments such as 'target += value'
+type AssignExpression struct {
+	Token    token.Token // '=', '+=', '-=', '*=' or '/='
+	Target   Expression  // Identifier or IndexExpression
+	Operator string
+	Value    Expression
+}
+
+// TokenLiteral return '=', '+=', etc...
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) Pos() token.Position  { return ae.Target.Pos() }
+func (ae *AssignExpression) End() token.Position {
+	if ae.Value != nil {
+		return ae.Value.End()
+	}
+	return ae.Token.End
+}
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + " ")
+	if ae.Value != nil { // HACK: nil check
+		out.WriteString(ae.Value.String())
+	}
+
+	return out.String()
+}
+func (ae *AssignExpression) expressionNode() {}
+
 // PrefixExpression for prefix of expression
 type PrefixExpression struct {
 	Token    token.Token // For example, !, -, etc...
@@ -145,6 +241,13 @@ type PrefixExpression struct {
 
 // TokenLiteral return prefix of expression string
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Position  { return pe.Token.Pos }
+func (pe *PrefixExpression) End() token.Position {
+	if pe.Right != nil {
+		return pe.Right.End()
+	}
+	return pe.Token.End
+}
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 
@@ -167,6 +270,13 @@ type InfixExpression struct {
 
 // TokenLiteral return  +, !=, etc...
 func (oe *InfixExpression) TokenLiteral() string { return oe.Token.Literal }
+func (oe *InfixExpression) Pos() token.Position  { return oe.Left.Pos() }
+func (oe *InfixExpression) End() token.Position {
+	if oe.Right != nil {
+		return oe.Right.End()
+	}
+	return oe.Token.End
+}
 func (oe *InfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -188,6 +298,8 @@ type Boolean struct {
 
 // TokenLiteral return 'true' or 'false'
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
+func (b *Boolean) Pos() token.Position  { return b.Token.Pos }
+func (b *Boolean) End() token.Position  { return b.Token.End }
 func (b *Boolean) String() string       { return b.Token.Literal }
 func (b *Boolean) expressionNode()      {}
 
@@ -201,6 +313,13 @@ type IfExpression struct {
 
 // TokenLiteral return 'if'
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() token.Position  { return ie.Token.Pos }
+func (ie *IfExpression) End() token.Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+	return ie.Consequence.End()
+}
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -222,10 +341,13 @@ func (ie *IfExpression) expressionNode() {}
 type BlockStatement struct {
 	Token      token.Token // '{'
 	Statements []Statement
+	Rbrace     token.Position // position immediately after '}'
 }
 
 // TokenLiteral return '{'
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Position  { return bs.Token.Pos }
+func (bs *BlockStatement) End() token.Position  { return bs.Rbrace }
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 
@@ -237,6 +359,154 @@ func (bs *BlockStatement) String() string {
 }
 func (bs *BlockStatement) statementNode() {}
 
+// WhileStatement for 'while (condition) { body }'
+type WhileStatement struct {
+	Token     token.Token // 'while'
+	Condition Expression
+	Body      *BlockStatement
+}
+
+// TokenLiteral return 'while'
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) Pos() token.Position  { return ws.Token.Pos }
+func (ws *WhileStatement) End() token.Position  { return ws.Body.End() }
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ws.Body.String())
+
+	return out.String()
+}
+func (ws *WhileStatement) statementNode() {}
+
+// ForStatement for 'for (init; condition; update) { body }'.
+// Init, Condition and Update are nil when omitted.
+type ForStatement struct {
+	Token     token.Token // 'for'
+	Init      Statement
+	Condition Expression
+	Update    Statement
+	Body      *BlockStatement
+}
+
+// TokenLiteral return 'for'
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) Pos() token.Position  { return fs.Token.Pos }
+func (fs *ForStatement) End() token.Position  { return fs.Body.End() }
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	if fs.Init != nil {
+		out.WriteString(strings.TrimSuffix(fs.Init.String(), ";"))
+	}
+	out.WriteString("; ")
+	if fs.Condition != nil {
+		out.WriteString(fs.Condition.String())
+	}
+	out.WriteString("; ")
+	if fs.Update != nil {
+		out.WriteString(strings.TrimSuffix(fs.Update.String(), ";"))
+	}
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+func (fs *ForStatement) statementNode() {}
+
+// ForInStatement for 'for (variable in iterable) { body }'
+type ForInStatement struct {
+	Token    token.Token // 'for'
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+// TokenLiteral return 'for'
+func (fi *ForInStatement) TokenLiteral() string { return fi.Token.Literal }
+func (fi *ForInStatement) Pos() token.Position  { return fi.Token.Pos }
+func (fi *ForInStatement) End() token.Position  { return fi.Body.End() }
+func (fi *ForInStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	out.WriteString(fi.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fi.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fi.Body.String())
+
+	return out.String()
+}
+func (fi *ForInStatement) statementNode() {}
+
+// BreakStatement for 'break'
+type BreakStatement struct {
+	Token token.Token // 'break'
+}
+
+// TokenLiteral return 'break'
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) Pos() token.Position  { return bs.Token.Pos }
+func (bs *BreakStatement) End() token.Position  { return bs.Token.End }
+func (bs *BreakStatement) String() string       { return "break;" }
+func (bs *BreakStatement) statementNode()       {}
+
+// ContinueStatement for 'continue'
+type ContinueStatement struct {
+	Token token.Token // 'continue'
+}
+
+// TokenLiteral return 'continue'
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) Pos() token.Position  { return cs.Token.Pos }
+func (cs *ContinueStatement) End() token.Position  { return cs.Token.End }
+func (cs *ContinueStatement) String() string       { return "continue;" }
+func (cs *ContinueStatement) statementNode()       {}
+
+// ImportStatement is for 'import "path"', which binds the module of the file to its name,
+// 'import "path" as name', and 'import { a, b } from "path"', which binds the exported a and b
+type ImportStatement struct {
+	Token token.Token // token.IMPORT
+	Path  *StringLiteral
+	Alias *Identifier   // the name given by "as", if any
+	Names []*Identifier // the names between '{' and '}', if any
+}
+
+// TokenLiteral return "import"
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) Pos() token.Position  { return is.Token.Pos }
+func (is *ImportStatement) End() token.Position {
+	if is.Alias != nil {
+		return is.Alias.End()
+	}
+	return is.Path.End()
+}
+func (is *ImportStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(is.TokenLiteral() + " ")
+	if len(is.Names) > 0 {
+		names := []string{}
+		for _, n := range is.Names {
+			names = append(names, n.String())
+		}
+		out.WriteString("{ " + strings.Join(names, ", ") + " } from ")
+	}
+	out.WriteString("\"" + is.Path.String() + "\"")
+	if is.Alias != nil {
+		out.WriteString(" as " + is.Alias.String())
+	}
+	out.WriteString(";")
+
+	return out.String()
+}
+func (is *ImportStatement) statementNode() {}
+
 // FunctionLiteral for 'fn'
 type FunctionLiteral struct {
 	Token      token.Token // 'fn'
@@ -246,6 +516,8 @@ type FunctionLiteral struct {
 
 // TokenLiteral return 'fn'
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() token.Position  { return fl.Token.Pos }
+func (fl *FunctionLiteral) End() token.Position  { return fl.Body.End() }
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
@@ -269,10 +541,13 @@ type CallExpression struct {
 	Token     token.Token // '('
 	Function  Expression  // Identifier or FunctionLiteral
 	Arguments []Expression
+	Rparen    token.Position // position immediately after ')'
 }
 
 // TokenLiteral return '('
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position  { return ce.Function.Pos() }
+func (ce *CallExpression) End() token.Position  { return ce.Rparen }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -298,6 +573,8 @@ type StringLiteral struct {
 
 // TokenLiteral return <string>
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() token.Position  { return sl.Token.Pos }
+func (sl *StringLiteral) End() token.Position  { return sl.Token.End }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 func (sl *StringLiteral) expressionNode()      {}
 
@@ -305,10 +582,13 @@ func (sl *StringLiteral) expressionNode()      {}
 type ArrayLiteral struct {
 	Token    token.Token // '['
 	Elements []Expression
+	Rbrack   token.Position // position immediately after ']'
 }
 
 // TokenLiteral return '['
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() token.Position  { return al.Token.Pos }
+func (al *ArrayLiteral) End() token.Position  { return al.Rbrack }
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
 
@@ -327,13 +607,16 @@ func (al *ArrayLiteral) expressionNode() {}
 
 // IndexExpression for call of array
 type IndexExpression struct {
-	Token token.Token // '['
-	Left  Expression
-	Index Expression
+	Token  token.Token // '['
+	Left   Expression
+	Index  Expression
+	Rbrack token.Position // position immediately after ']'
 }
 
 // TokenLiteral return '['
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position  { return ie.Left.Pos() }
+func (ie *IndexExpression) End() token.Position  { return ie.Rbrack }
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 
@@ -347,14 +630,33 @@ func (ie *IndexExpression) String() string {
 }
 func (ie *IndexExpression) expressionNode() {}
 
+// PropertyExpression is for 'left.name', the value of the key "name" of a hash or a member of a module
+type PropertyExpression struct {
+	Token    token.Token // '.'
+	Left     Expression
+	Property *Identifier
+}
+
+// TokenLiteral return "."
+func (pe *PropertyExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PropertyExpression) Pos() token.Position  { return pe.Left.Pos() }
+func (pe *PropertyExpression) End() token.Position  { return pe.Property.End() }
+func (pe *PropertyExpression) String() string {
+	return "(" + pe.Left.String() + "." + pe.Property.String() + ")"
+}
+func (pe *PropertyExpression) expressionNode() {}
+
 // HashLiteral for hash
 type HashLiteral struct {
-	Token token.Token // '{'
-	Pairs map[Expression]Expression
+	Token  token.Token // '{'
+	Pairs  map[Expression]Expression
+	Rbrace token.Position // position immediately after '}'
 }
 
 // TokenLiteral return '{'
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() token.Position  { return hl.Token.Pos }
+func (hl *HashLiteral) End() token.Position  { return hl.Rbrace }
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
@@ -380,6 +682,8 @@ type MacroLiteral struct {
 
 // TokenLiteral return 'macro'
 func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) Pos() token.Position  { return ml.Token.Pos }
+func (ml *MacroLiteral) End() token.Position  { return ml.Body.End() }
 func (ml *MacroLiteral) String() string {
 	var out bytes.Buffer
 
@@ -0,0 +1,275 @@
+package code
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/NAKKA-K/learn-interpreter-in-go/token"
+)
+
+// Instructions is a sequence of encoded instructions
+type Instructions []byte
+
+// String return disassembled instructions, one per line
+func (ins Instructions) String() string {
+	var out bytes.Buffer
+
+	i := 0
+	for i < len(ins) {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+
+		operands, read := ReadOperands(def, ins[i+1:])
+
+		fmt.Fprintf(&out, "%04d %s\n", i, ins.fmtInstruction(def, operands))
+
+		i += 1 + read
+	}
+
+	return out.String()
+}
+
+func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
+	operandCount := len(def.OperandWidths)
+
+	if len(operands) != operandCount {
+		return fmt.Sprintf("ERROR: operand len %d does not match defined %d\n", len(operands), operandCount)
+	}
+
+	switch operandCount {
+	case 0:
+		return def.Name
+	case 1:
+		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
+	}
+
+	return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", def.Name)
+}
+
+// Opcode is the first byte of an instruction
+type Opcode byte
+
+const (
+	OpConstant Opcode = iota // push constants[operand]
+	OpPop                    // discard the top of the stack
+
+	OpAdd // arithmetic on the two topmost values
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+
+	OpTrue  // push TRUE
+	OpFalse // push FALSE
+	OpNull  // push NULL
+
+	OpEqual // comparison of the two topmost values
+	OpNotEqual
+	OpGreaterThan
+	OpGreaterEqual
+	OpLessThan
+	OpLessEqual
+
+	OpMinus // -x
+	OpBang  // !x
+
+	OpJump               // jump to operand
+	OpJumpNotTruthy      // pop the condition, and jump to operand if it is falsy
+	OpJumpTruthyOrPop    // for ||: jump keeping the value if it is truthy, otherwise pop it
+	OpJumpNotTruthyOrPop // for &&: jump keeping the value if it is falsy, otherwise pop it
+	OpGetGlobal          // push globals[operand]
+	OpSetGlobal          // pop into globals[operand]
+	OpGetLocal           // push the operand-th local of the current frame
+	OpSetLocal           // pop into the operand-th local of the current frame
+	OpGetFree            // push the operand-th captured variable of the current closure
+	OpSetFree            // pop into the operand-th captured variable of the current closure
+	OpGetBuiltin         // push object.Builtins[operand]
+	OpFail               // fail with the message constants[operand]
+	OpArray              // build an array from the operand topmost values
+	OpHash               // build a hash from the operand topmost values (key, value, key, value...)
+	OpIndex              // pop index and container, and push container[index]
+	OpGetProperty        // pop a hash, and push its value at the key constants[operand], which must be present
+	OpSetIndex           // pop value, index and container, set container[index] and push value
+	OpSetProperty        // pop value and hash, set the key constants[operand] of the hash and push value
+	OpDup                // push the topmost value again
+	OpDup2               // push the two topmost values again
+	OpCall               // call the function below the operand topmost arguments
+	OpTailCall           // OpCall whose result is returned at once, which replaces the frame of the caller
+	OpReturnValue        // return the top of the stack from the current function
+	OpReturn             // return NULL from the current function
+	OpClosure            // build a closure of constants[first operand] from second operand captured variables
+	OpCaptureLocal       // push a reference to the operand-th local of the current frame
+	OpCaptureFree        // push the reference held by the operand-th captured variable
+	OpIter               // replace an array, string or hash with an iterator over it
+	OpIterNext           // pop an iterator, and push its next item or jump to operand when exhausted
+)
+
+// Definition is name and operand widths of an opcode
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant: {"OpConstant", []int{2}},
+	OpPop:      {"OpPop", []int{}},
+
+	OpAdd: {"OpAdd", []int{}},
+	OpSub: {"OpSub", []int{}},
+	OpMul: {"OpMul", []int{}},
+	OpDiv: {"OpDiv", []int{}},
+	OpMod: {"OpMod", []int{}},
+
+	OpTrue:  {"OpTrue", []int{}},
+	OpFalse: {"OpFalse", []int{}},
+	OpNull:  {"OpNull", []int{}},
+
+	OpEqual:        {"OpEqual", []int{}},
+	OpNotEqual:     {"OpNotEqual", []int{}},
+	OpGreaterThan:  {"OpGreaterThan", []int{}},
+	OpGreaterEqual: {"OpGreaterEqual", []int{}},
+	OpLessThan:     {"OpLessThan", []int{}},
+	OpLessEqual:    {"OpLessEqual", []int{}},
+
+	OpMinus: {"OpMinus", []int{}},
+	OpBang:  {"OpBang", []int{}},
+
+	OpJump:               {"OpJump", []int{2}},
+	OpJumpNotTruthy:      {"OpJumpNotTruthy", []int{2}},
+	OpJumpTruthyOrPop:    {"OpJumpTruthyOrPop", []int{2}},
+	OpJumpNotTruthyOrPop: {"OpJumpNotTruthyOrPop", []int{2}},
+	OpGetGlobal:          {"OpGetGlobal", []int{2}},
+	OpSetGlobal:          {"OpSetGlobal", []int{2}},
+	OpGetLocal:           {"OpGetLocal", []int{1}},
+	OpSetLocal:           {"OpSetLocal", []int{1}},
+	OpGetFree:            {"OpGetFree", []int{1}},
+	OpSetFree:            {"OpSetFree", []int{1}},
+	OpGetBuiltin:         {"OpGetBuiltin", []int{1}},
+	OpFail:               {"OpFail", []int{2}},
+	OpArray:              {"OpArray", []int{2}},
+	OpHash:               {"OpHash", []int{2}},
+	OpIndex:              {"OpIndex", []int{}},
+	OpGetProperty:        {"OpGetProperty", []int{2}},
+	OpSetIndex:           {"OpSetIndex", []int{}},
+	OpSetProperty:        {"OpSetProperty", []int{2}},
+	OpDup:                {"OpDup", []int{}},
+	OpDup2:               {"OpDup2", []int{}},
+	OpCall:               {"OpCall", []int{1}},
+	OpTailCall:           {"OpTailCall", []int{1}},
+	OpReturnValue:        {"OpReturnValue", []int{}},
+	OpReturn:             {"OpReturn", []int{}},
+	OpClosure:            {"OpClosure", []int{2, 1}},
+	OpCaptureLocal:       {"OpCaptureLocal", []int{1}},
+	OpCaptureFree:        {"OpCaptureFree", []int{1}},
+	OpIter:               {"OpIter", []int{}},
+	OpIterNext:           {"OpIterNext", []int{2}},
+}
+
+// Lookup return the definition of op
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+
+	return def, nil
+}
+
+// Check return an error if an operand of op does not fit in its width, which Make would truncate
+func Check(op Opcode, operands ...int) error {
+	def, ok := definitions[op]
+	if !ok {
+		return fmt.Errorf("opcode %d undefined", op)
+	}
+
+	for i, o := range operands {
+		max := 1<<(8*uint(def.OperandWidths[i])) - 1
+		if o < 0 || o > max {
+			return fmt.Errorf("operand %d of %s is out of range 0-%d", o, def.Name, max)
+		}
+	}
+	return nil
+}
+
+// Make encode an instruction
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	instructionLen := 1
+	for _, w := range def.OperandWidths {
+		instructionLen += w
+	}
+
+	instruction := make([]byte, instructionLen)
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, o := range operands {
+		width := def.OperandWidths[i]
+		switch width {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+		offset += width
+	}
+
+	return instruction
+}
+
+// ReadOperands decode the operands of an instruction, and return them with the number of bytes read
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		}
+
+		offset += width
+	}
+
+	return operands, offset
+}
+
+// ReadUint16 decode a 2 byte operand
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+// ReadUint8 decode a 1 byte operand
+func ReadUint8(ins Instructions) uint8 { return uint8(ins[0]) }
+
+// SourceMap map instruction offsets to the source positions they were compiled from
+type SourceMap []SourcePos
+
+// SourcePos is the position of the instructions starting at Offset
+type SourcePos struct {
+	Offset int
+	Pos    token.Position
+}
+
+// Lookup return the position of the instruction at offset
+func (m SourceMap) Lookup(offset int) token.Position {
+	i := sort.Search(len(m), func(i int) bool { return m[i].Offset > offset })
+	if i == 0 {
+		return token.Position{}
+	}
+	return m[i-1].Pos
+}
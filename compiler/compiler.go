@@ -0,0 +1,850 @@
+package compiler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NAKKA-K/learn-interpreter-in-go/ast"
+	"github.com/NAKKA-K/learn-interpreter-in-go/code"
+	"github.com/NAKKA-K/learn-interpreter-in-go/object"
+)
+
+// iteratorName is the hidden variable holding the iterator of a for-in loop. It cannot clash with an identifier.
+const iteratorName = "<iterator>"
+
+// EmittedInstruction is an instruction which has been emitted
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
+}
+
+// CompilationScope is the instructions of the function being compiled
+type CompilationScope struct {
+	instructions        code.Instructions
+	sourceMap           code.SourceMap
+	lastInstruction     EmittedInstruction
+	previousInstruction EmittedInstruction
+	loops               []*loop
+}
+
+// loop is the jumps of a loop which are patched when the loop has been compiled
+type loop struct {
+	breaks    []int
+	continues []int
+}
+
+// Compiler compile an ast.Program to bytecode
+type Compiler struct {
+	constants []object.Object
+
+	symbolTable *SymbolTable
+
+	scopes     []CompilationScope
+	scopeIndex int
+
+	err error // the first operand which does not fit in an instruction
+}
+
+// New return a Compiler with the builtin functions defined
+func New() *Compiler {
+	symbolTable := NewSymbolTable()
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+
+	return NewWithState(symbolTable, []object.Object{})
+}
+
+// NewWithState return a Compiler which continues from the globals and constants of an earlier compilation
+func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
+	return &Compiler{
+		constants:   constants,
+		symbolTable: s,
+		scopes:      []CompilationScope{{}},
+	}
+}
+
+// Bytecode is the result of a compilation
+type Bytecode struct {
+	Instructions code.Instructions
+	Constants    []object.Object
+	SourceMap    code.SourceMap
+	GlobalNames  []string // names of the globals, by index
+}
+
+// Bytecode return the compiled program
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Constants:    c.constants,
+		SourceMap:    c.scopes[c.scopeIndex].sourceMap,
+		GlobalNames:  c.symbolTable.Names(),
+	}
+}
+
+// Compile node. The value of the last expression statement of a program is returned by the VM.
+func (c *Compiler) Compile(node ast.Node) error {
+	switch node := node.(type) {
+
+	// Statements
+	case *ast.Program:
+		c.hoist(node.Statements)
+		for _, s := range node.Statements {
+			if err := c.Compile(s); err != nil {
+				return err
+			}
+		}
+		if c.lastInstructionIs(code.OpPop) {
+			c.replaceLastPopWithReturn()
+		}
+
+	case *ast.ExpressionStatement:
+		if err := c.Compile(node.Expression); err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			if err := c.Compile(s); err != nil {
+				return err
+			}
+		}
+
+	case *ast.LetStatement:
+		return c.compileLetStatement(node)
+
+	case *ast.ReturnStatement:
+		if err := c.Compile(node.ReturnValue); err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+
+	case *ast.WhileStatement:
+		return c.compileWhileStatement(node)
+
+	case *ast.ForStatement:
+		return c.compileForStatement(node)
+
+	case *ast.ForInStatement:
+		return c.compileForInStatement(node)
+
+	case *ast.BreakStatement:
+		l := c.currentLoop()
+		l.breaks = append(l.breaks, c.emit(code.OpJump, 9999))
+
+	case *ast.ContinueStatement:
+		l := c.currentLoop()
+		l.continues = append(l.continues, c.emit(code.OpJump, 9999))
+
+	case *ast.ImportStatement:
+		return fmt.Errorf("%s: import is only supported by the evaluator", node.Pos())
+
+	// Expressions
+	case *ast.IfExpression:
+		return c.compileIfExpression(node)
+
+	case *ast.InfixExpression:
+		return c.compileInfixExpression(node)
+
+	case *ast.AssignExpression:
+		return c.compileAssignExpression(node)
+
+	case *ast.PrefixExpression:
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+
+		c.mark(node)
+		switch node.Operator {
+		case "!":
+			c.emit(code.OpBang)
+		case "-":
+			c.emit(code.OpMinus)
+		default:
+			return fmt.Errorf("%s: unknown operator %s", node.Pos(), node.Operator)
+		}
+
+	case *ast.FunctionLiteral:
+		return c.compileFunctionLiteral(node, "")
+
+	case *ast.CallExpression:
+		if node.Function.TokenLiteral() == "quote" {
+			return fmt.Errorf("%s: quote is only supported by the evaluator", node.Pos())
+		}
+
+		if err := c.Compile(node.Function); err != nil {
+			return err
+		}
+		for _, a := range node.Arguments {
+			if err := c.Compile(a); err != nil {
+				return err
+			}
+		}
+
+		c.mark(node)
+		c.emit(code.OpCall, len(node.Arguments))
+
+	case *ast.IndexExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Index); err != nil {
+			return err
+		}
+
+		c.mark(node)
+		c.emit(code.OpIndex)
+
+	case *ast.PropertyExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+
+		c.mark(node.Property)
+		c.emit(code.OpGetProperty, c.addConstant(&object.String{Value: node.Property.Value}))
+
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(node.Value)
+		if !ok {
+			c.fail(node, "identifier not found: "+node.Value)
+			return nil
+		}
+		c.mark(node)
+		c.loadSymbol(symbol)
+
+	case *ast.IntegerLiteral:
+		c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: node.Value}))
+
+	case *ast.FloatLiteral:
+		c.emit(code.OpConstant, c.addConstant(&object.Float{Value: node.Value}))
+
+	case *ast.StringLiteral:
+		c.emit(code.OpConstant, c.addConstant(&object.String{Value: node.Value}))
+
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			if err := c.Compile(el); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpArray, len(node.Elements))
+
+	case *ast.HashLiteral:
+		keys := []ast.Expression{}
+		for k := range node.Pairs {
+			keys = append(keys, k)
+		}
+		// The order of a Go map is random, so sort the keys to make the bytecode reproducible
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+
+		for _, k := range keys {
+			if err := c.Compile(k); err != nil {
+				return err
+			}
+			if err := c.Compile(node.Pairs[k]); err != nil {
+				return err
+			}
+		}
+
+		c.mark(node)
+		c.emit(code.OpHash, len(node.Pairs)*2)
+
+	default:
+		return fmt.Errorf("%s: cannot compile %T", node.Pos(), node)
+	}
+
+	return c.err
+}
+
+// hoist define the names bound by let statements of a program, a function or a loop body before compiling it,
+// so that a function can refer to a name defined after the function.
+// The blocks of if and while share the enclosing scope, so their let statements are hoisted too.
+func (c *Compiler) hoist(statements []ast.Statement) {
+	for _, s := range statements {
+		switch s := s.(type) {
+		case *ast.LetStatement:
+			c.symbolTable.DefineHoisted(s.Name.Value)
+			c.hoistExpression(s.Value)
+		case *ast.ExpressionStatement:
+			c.hoistExpression(s.Expression)
+		case *ast.WhileStatement:
+			c.hoist(s.Body.Statements)
+		}
+	}
+}
+
+func (c *Compiler) hoistExpression(exp ast.Expression) {
+	if ie, ok := exp.(*ast.IfExpression); ok {
+		c.hoist(ie.Consequence.Statements)
+		if ie.Alternative != nil {
+			c.hoist(ie.Alternative.Statements)
+		}
+	}
+}
+
+func (c *Compiler) compileLetStatement(node *ast.LetStatement) error {
+	// A function is defined before it is compiled, so that it can call itself.
+	// Any other value is compiled first, so that `let x = x + 1` refers to the outer x.
+	if fl, ok := node.Value.(*ast.FunctionLiteral); ok {
+		symbol := c.symbolTable.Define(node.Name.Value)
+		if err := c.compileFunctionLiteral(fl, node.Name.Value); err != nil {
+			return err
+		}
+		c.storeSymbol(symbol)
+		return nil
+	}
+
+	if err := c.Compile(node.Value); err != nil {
+		return err
+	}
+	c.storeSymbol(c.symbolTable.Define(node.Name.Value))
+	return nil
+}
+
+func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
+	if err := c.Compile(node.Condition); err != nil {
+		return err
+	}
+
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	if err := c.compileBlockValue(node.Consequence); err != nil {
+		return err
+	}
+
+	jumpPos := c.emit(code.OpJump, 9999)
+	c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+
+	if node.Alternative == nil {
+		c.emit(code.OpNull)
+	} else if err := c.compileBlockValue(node.Alternative); err != nil {
+		return err
+	}
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+	return nil
+}
+
+// compileBlockValue compile a block which leaves the value of its last statement on the stack
+func (c *Compiler) compileBlockValue(block *ast.BlockStatement) error {
+	start := len(c.currentInstructions())
+
+	if err := c.Compile(block); err != nil {
+		return err
+	}
+
+	if len(c.currentInstructions()) > start && c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+	return nil
+}
+
+func (c *Compiler) compileWhileStatement(node *ast.WhileStatement) error {
+	loopStart := len(c.currentInstructions())
+
+	if err := c.Compile(node.Condition); err != nil {
+		return err
+	}
+	jumpExitPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	c.enterLoop()
+	if err := c.Compile(node.Body); err != nil {
+		return err
+	}
+	c.emit(code.OpJump, loopStart)
+
+	c.leaveLoop(loopStart, jumpExitPos)
+	return nil
+}
+
+func (c *Compiler) compileForStatement(node *ast.ForStatement) error {
+	c.enterBlock()
+
+	if node.Init != nil {
+		if err := c.Compile(node.Init); err != nil {
+			return err
+		}
+	}
+
+	loopStart := len(c.currentInstructions())
+
+	jumpExitPos := -1
+	if node.Condition != nil {
+		if err := c.Compile(node.Condition); err != nil {
+			return err
+		}
+		jumpExitPos = c.emit(code.OpJumpNotTruthy, 9999)
+	}
+
+	c.hoist(node.Body.Statements)
+	c.enterLoop()
+	if err := c.Compile(node.Body); err != nil {
+		return err
+	}
+
+	updateStart := len(c.currentInstructions())
+	if node.Update != nil {
+		if err := c.Compile(node.Update); err != nil {
+			return err
+		}
+	}
+	c.emit(code.OpJump, loopStart)
+
+	c.leaveLoop(updateStart, jumpExitPos)
+	c.leaveBlock()
+	return nil
+}
+
+func (c *Compiler) compileForInStatement(node *ast.ForInStatement) error {
+	if err := c.Compile(node.Iterable); err != nil {
+		return err
+	}
+	c.mark(node.Iterable)
+	c.emit(code.OpIter)
+
+	c.enterBlock()
+
+	iterator := c.symbolTable.Define(iteratorName)
+	c.storeSymbol(iterator)
+
+	loopStart := len(c.currentInstructions())
+	c.loadSymbol(iterator)
+	jumpExitPos := c.emit(code.OpIterNext, 9999)
+	c.storeSymbol(c.symbolTable.Define(node.Variable.Value))
+
+	c.hoist(node.Body.Statements)
+	c.enterLoop()
+	if err := c.Compile(node.Body); err != nil {
+		return err
+	}
+	c.emit(code.OpJump, loopStart)
+
+	c.leaveLoop(loopStart, jumpExitPos)
+	c.leaveBlock()
+	return nil
+}
+
+func (c *Compiler) compileInfixExpression(node *ast.InfixExpression) error {
+	if err := c.Compile(node.Left); err != nil {
+		return err
+	}
+
+	// The operand which decides the value of && and || is left on the stack
+	if node.Operator == "&&" || node.Operator == "||" {
+		op := code.OpJumpNotTruthyOrPop
+		if node.Operator == "||" {
+			op = code.OpJumpTruthyOrPop
+		}
+
+		jumpPos := c.emit(op, 9999)
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+		c.changeOperand(jumpPos, len(c.currentInstructions()))
+		return nil
+	}
+
+	if err := c.Compile(node.Right); err != nil {
+		return err
+	}
+
+	c.mark(node)
+	return c.emitOperator(node.Operator, node)
+}
+
+var infixOpcodes = map[string]code.Opcode{
+	"+":  code.OpAdd,
+	"-":  code.OpSub,
+	"*":  code.OpMul,
+	"/":  code.OpDiv,
+	"%":  code.OpMod,
+	"==": code.OpEqual,
+	"!=": code.OpNotEqual,
+	">":  code.OpGreaterThan,
+	">=": code.OpGreaterEqual,
+	"<":  code.OpLessThan,
+	"<=": code.OpLessEqual,
+}
+
+func (c *Compiler) emitOperator(operator string, node ast.Node) error {
+	op, ok := infixOpcodes[operator]
+	if !ok {
+		return fmt.Errorf("%s: unknown operator %s", node.Pos(), operator)
+	}
+	c.emit(op)
+	return nil
+}
+
+// compileAssignExpression compile "=" and the compound assignments, which leave the assigned value on the stack
+func (c *Compiler) compileAssignExpression(node *ast.AssignExpression) error {
+	operator := strings.TrimSuffix(node.Operator, "=")
+
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(target.Value)
+		if !ok || symbol.Scope == BuiltinScope {
+			if node.Operator != "=" {
+				c.fail(target, "identifier not found: "+target.Value)
+			} else {
+				c.fail(target, "assignment to undeclared identifier: "+target.Value)
+			}
+			return nil
+		}
+
+		if node.Operator != "=" {
+			c.mark(target)
+			c.loadSymbol(symbol)
+		}
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		if node.Operator != "=" {
+			c.mark(node)
+			if err := c.emitOperator(operator, node); err != nil {
+				return err
+			}
+		}
+
+		c.storeSymbol(symbol)
+		c.loadSymbol(symbol)
+
+	case *ast.IndexExpression:
+		if err := c.Compile(target.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(target.Index); err != nil {
+			return err
+		}
+
+		if node.Operator != "=" {
+			c.emit(code.OpDup2)
+			c.mark(target)
+			c.emit(code.OpIndex)
+		}
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		if node.Operator != "=" {
+			c.mark(node)
+			if err := c.emitOperator(operator, node); err != nil {
+				return err
+			}
+		}
+
+		c.mark(target)
+		c.emit(code.OpSetIndex)
+
+	case *ast.PropertyExpression:
+		if err := c.Compile(target.Left); err != nil {
+			return err
+		}
+		name := c.addConstant(&object.String{Value: target.Property.Value})
+
+		if node.Operator != "=" {
+			c.emit(code.OpDup)
+			c.mark(target.Property)
+			c.emit(code.OpGetProperty, name)
+		}
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		if node.Operator != "=" {
+			c.mark(node)
+			if err := c.emitOperator(operator, node); err != nil {
+				return err
+			}
+		}
+
+		c.mark(target.Property)
+		c.emit(code.OpSetProperty, name)
+
+	default:
+		c.fail(node, "cannot assign to "+node.Target.String())
+	}
+
+	return nil
+}
+
+// compileFunctionLiteral compile fn to a closure. name is the let binding of the function, if any.
+func (c *Compiler) compileFunctionLiteral(node *ast.FunctionLiteral, name string) error {
+	c.enterScope()
+
+	for _, p := range node.Parameters {
+		c.symbolTable.Define(p.Value)
+	}
+	c.hoist(node.Body.Statements)
+
+	if err := c.Compile(node.Body); err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.replaceLastPopWithReturn()
+	}
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+	markTailCalls(c.currentInstructions())
+
+	freeSymbols := c.symbolTable.FreeSymbols
+	freeNames := make([]string, len(freeSymbols))
+	for i, s := range freeSymbols {
+		freeNames[i] = s.Name
+	}
+
+	numLocals := c.symbolTable.NumDefinitions()
+	localNames := c.symbolTable.Names()
+	sourceMap := c.scopes[c.scopeIndex].sourceMap
+	instructions := c.leaveScope()
+
+	if numLocals > 255 {
+		return fmt.Errorf("%s: too many local variables in function", node.Pos())
+	}
+
+	for _, s := range freeSymbols {
+		if s.Scope == FreeScope {
+			c.emit(code.OpCaptureFree, s.Index)
+		} else {
+			c.emit(code.OpCaptureLocal, s.Index)
+		}
+	}
+
+	compiledFn := &object.CompiledFunction{
+		Name:          name,
+		Instructions:  instructions,
+		NumLocals:     numLocals,
+		NumParameters: len(node.Parameters),
+		SourceMap:     sourceMap,
+		LocalNames:    localNames,
+		FreeNames:     freeNames,
+	}
+
+	c.emit(code.OpClosure, c.addConstant(compiledFn), len(freeSymbols))
+	return nil
+}
+
+// markTailCalls replace OpCall with OpTailCall where the result of the call is returned at once,
+// either by the next instruction or at the end of the jumps following the call,
+// such as `return f(x)` and the last expression of an if branch.
+func markTailCalls(ins code.Instructions) {
+	for ip := 0; ip < len(ins); {
+		op := code.Opcode(ins[ip])
+		def, _ := code.Lookup(ins[ip])
+		_, read := code.ReadOperands(def, ins[ip+1:])
+		next := ip + 1 + read
+
+		if op == code.OpCall && returnsAt(ins, next) {
+			ins[ip] = byte(code.OpTailCall)
+		}
+
+		ip = next
+	}
+}
+
+// returnsAt report whether the instruction at ip returns the top of the stack, possibly after jumps
+func returnsAt(ins code.Instructions, ip int) bool {
+	for jumps := 0; ip < len(ins) && jumps < 16; jumps++ {
+		switch code.Opcode(ins[ip]) {
+		case code.OpReturnValue:
+			return true
+		case code.OpJump:
+			ip = int(code.ReadUint16(ins[ip+1:]))
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case BuiltinScope:
+		c.emit(code.OpGetBuiltin, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	}
+}
+
+func (c *Compiler) storeSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpSetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpSetLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpSetFree, s.Index)
+	}
+}
+
+// fail emit an instruction which raises a runtime error with message at node.
+// Errors such as an unknown identifier are raised when they are reached, as in the evaluator.
+func (c *Compiler) fail(node ast.Node, message string) {
+	c.mark(node)
+	c.emit(code.OpFail, c.addConstant(&object.String{Value: message}))
+}
+
+// mark record that the next instruction was compiled from node, so that its runtime errors have a position
+func (c *Compiler) mark(node ast.Node) {
+	scope := &c.scopes[c.scopeIndex]
+	scope.sourceMap = append(scope.sourceMap, code.SourcePos{
+		Offset: len(scope.instructions),
+		Pos:    node.Pos(),
+	})
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	c.check(op, operands...)
+	ins := code.Make(op, operands...)
+	pos := c.addInstruction(ins)
+
+	c.setLastInstruction(op, pos)
+
+	return pos
+}
+
+func (c *Compiler) addInstruction(ins []byte) int {
+	posNewInstruction := len(c.currentInstructions())
+	c.scopes[c.scopeIndex].instructions = append(c.currentInstructions(), ins...)
+	return posNewInstruction
+}
+
+func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
+	previous := c.scopes[c.scopeIndex].lastInstruction
+	last := EmittedInstruction{Opcode: op, Position: pos}
+
+	c.scopes[c.scopeIndex].previousInstruction = previous
+	c.scopes[c.scopeIndex].lastInstruction = last
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+func (c *Compiler) removeLastPop() {
+	last := c.scopes[c.scopeIndex].lastInstruction
+	previous := c.scopes[c.scopeIndex].previousInstruction
+
+	c.scopes[c.scopeIndex].instructions = c.currentInstructions()[:last.Position]
+	c.scopes[c.scopeIndex].lastInstruction = previous
+}
+
+func (c *Compiler) replaceLastPopWithReturn() {
+	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
+	c.replaceInstruction(lastPos, code.Make(code.OpReturnValue))
+
+	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+}
+
+func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+	ins := c.currentInstructions()
+
+	for i := 0; i < len(newInstruction); i++ {
+		ins[pos+i] = newInstruction[i]
+	}
+}
+
+func (c *Compiler) changeOperand(opPos int, operand int) {
+	op := code.Opcode(c.currentInstructions()[opPos])
+	c.check(op, operand)
+	newInstruction := code.Make(op, operand)
+
+	c.replaceInstruction(opPos, newInstruction)
+}
+
+// check record an error, which Compile returns, when the operands of op do not fit in the instruction.
+// The program is then too large for the VM: too many constants, globals, locals or arguments,
+// a too long jump, or a too long array literal.
+func (c *Compiler) check(op code.Opcode, operands ...int) {
+	if err := code.Check(op, operands...); err != nil && c.err == nil {
+		c.err = fmt.Errorf("program too large: %s", err)
+	}
+}
+
+func (c *Compiler) enterScope() {
+	c.scopes = append(c.scopes, CompilationScope{})
+	c.scopeIndex++
+
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+
+	c.symbolTable = c.symbolTable.Outer
+
+	return instructions
+}
+
+// enterBlock start a scope which shares the slots of the enclosing function
+func (c *Compiler) enterBlock() {
+	c.symbolTable = NewBlockSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveBlock() {
+	c.symbolTable = c.symbolTable.Outer
+}
+
+func (c *Compiler) enterLoop() {
+	scope := &c.scopes[c.scopeIndex]
+	scope.loops = append(scope.loops, &loop{})
+}
+
+// leaveLoop patch the jumps of the loop: continue jumps to continuePos, and break and jumpExitPos jump after the loop.
+// A loop statement leaves NULL as its value, as in the evaluator.
+func (c *Compiler) leaveLoop(continuePos int, jumpExitPos int) {
+	scope := &c.scopes[c.scopeIndex]
+	l := scope.loops[len(scope.loops)-1]
+	scope.loops = scope.loops[:len(scope.loops)-1]
+
+	exit := len(c.currentInstructions())
+	if jumpExitPos >= 0 {
+		c.changeOperand(jumpExitPos, exit)
+	}
+	for _, pos := range l.breaks {
+		c.changeOperand(pos, exit)
+	}
+	for _, pos := range l.continues {
+		c.changeOperand(pos, continuePos)
+	}
+
+	c.emit(code.OpNull)
+	c.emit(code.OpPop)
+}
+
+func (c *Compiler) currentLoop() *loop {
+	loops := c.scopes[c.scopeIndex].loops
+	return loops[len(loops)-1]
+}
@@ -0,0 +1,235 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/NAKKA-K/learn-interpreter-in-go/code"
+	"github.com/NAKKA-K/learn-interpreter-in-go/lexer"
+	"github.com/NAKKA-K/learn-interpreter-in-go/object"
+	"github.com/NAKKA-K/learn-interpreter-in-go/parser"
+)
+
+func TestInstructions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []code.Instructions
+	}{
+		{
+			"1 + 2; 3",
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpReturnValue),
+			},
+		},
+		{
+			"if (true) { 10 }; 3333;",
+			[]code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthy, 10),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpJump, 11),
+				code.Make(code.OpNull),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpReturnValue),
+			},
+		},
+		{
+			"let x = 1; x || 2",
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpJumpTruthyOrPop, 15),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpReturnValue),
+			},
+		},
+		{
+			"while (true) { break; }",
+			[]code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthy, 10),
+				code.Make(code.OpJump, 10),
+				code.Make(code.OpJump, 0),
+				code.Make(code.OpNull),
+				code.Make(code.OpReturnValue),
+			},
+		},
+		{
+			"undefined",
+			[]code.Instructions{
+				code.Make(code.OpFail, 0),
+				code.Make(code.OpReturnValue),
+			},
+		},
+		{
+			`{"a": {"b": 1}}.a.b`,
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpHash, 2),
+				code.Make(code.OpHash, 2),
+				code.Make(code.OpGetProperty, 3),
+				code.Make(code.OpGetProperty, 4),
+				code.Make(code.OpReturnValue),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		bytecode := compile(t, tt.input)
+
+		expected := code.Instructions{}
+		for _, ins := range tt.expected {
+			expected = append(expected, ins...)
+		}
+
+		if bytecode.Instructions.String() != expected.String() {
+			t.Errorf("wrong instructions for %q.\nwant=\n%s\ngot=\n%s", tt.input, expected, bytecode.Instructions)
+		}
+	}
+}
+
+func TestClosures(t *testing.T) {
+	bytecode := compile(t, "fn(a) { fn(b) { a + b } }")
+
+	inner, ok := bytecode.Constants[0].(*object.CompiledFunction)
+	if !ok {
+		t.Fatalf("constant 0 is not CompiledFunction. got=%T", bytecode.Constants[0])
+	}
+
+	expected := code.Instructions{}
+	for _, ins := range []code.Instructions{
+		code.Make(code.OpGetFree, 0),
+		code.Make(code.OpGetLocal, 0),
+		code.Make(code.OpAdd),
+		code.Make(code.OpReturnValue),
+	} {
+		expected = append(expected, ins...)
+	}
+	if inner.Instructions.String() != expected.String() {
+		t.Errorf("wrong instructions of the inner function.\nwant=\n%s\ngot=\n%s", expected, inner.Instructions)
+	}
+
+	outer := bytecode.Constants[1].(*object.CompiledFunction)
+	if !strings.Contains(outer.Instructions.String(), "OpCaptureLocal 0\n") ||
+		!strings.Contains(outer.Instructions.String(), "OpClosure 0 1\n") {
+		t.Errorf("outer function does not capture a.\n%s", outer.Instructions)
+	}
+}
+
+func TestFunctionNames(t *testing.T) {
+	bytecode := compile(t, "let add = fn(a, b) { a + b };")
+
+	fn := bytecode.Constants[0].(*object.CompiledFunction)
+	if fn.Name != "add" || fn.NumParameters != 2 || fn.NumLocals != 2 {
+		t.Errorf("wrong function. got name=%q, parameters=%d, locals=%d", fn.Name, fn.NumParameters, fn.NumLocals)
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		tailCall bool
+	}{
+		{"fn(n) { f(n) }", true},
+		{"fn(n) { return f(n); }", true},
+		{"fn(n) { if (n) { f(n) } else { 1 } }", true},
+		{"fn(n) { 1 + f(n) }", false},
+		{"fn(n) { let x = f(n); x }", false},
+	}
+
+	for _, tt := range tests {
+		bytecode := compile(t, "let f = fn(n) { n }; "+tt.input)
+
+		fn := bytecode.Constants[len(bytecode.Constants)-1].(*object.CompiledFunction)
+		if got := strings.Contains(fn.Instructions.String(), "OpTailCall"); got != tt.tailCall {
+			t.Errorf("wrong tail call for %q. expected=%t, got=\n%s", tt.input, tt.tailCall, fn.Instructions)
+		}
+	}
+}
+
+func TestSourceMap(t *testing.T) {
+	bytecode := compile(t, "let x = 1;\nx + true")
+
+	// OpConstant 0, OpSetGlobal 0, OpGetGlobal 0, OpTrue, OpAdd
+	if pos := bytecode.SourceMap.Lookup(10); pos.String() != "2:1" {
+		t.Errorf("wrong position of OpAdd. expected=2:1, got=%s", pos)
+	}
+}
+
+func TestProgramTooLarge(t *testing.T) {
+	locals := []string{}
+	for i := 0; i < 300; i++ {
+		locals = append(locals, fmt.Sprintf("let _%s = true;", letters(i)))
+	}
+	globals := []string{}
+	for i := 0; i < 70000; i++ {
+		globals = append(globals, fmt.Sprintf("let _%s = true;", letters(i)))
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{strings.Repeat("1;", 70000), "OpConstant"},
+		{strings.Join(globals, ""), "OpSetGlobal"},
+		{"fn() { " + strings.Join(locals, "") + " }", "OpSetLocal"},
+		{"fn() {}(true" + strings.Repeat(", true", 300) + ")", "OpCall"},
+		{"if (true) { " + strings.Repeat("true;", 70000) + " }", "OpJump"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+
+		err := New().Compile(program)
+		if err == nil || !strings.Contains(err.Error(), "program too large") || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("expected an error about %s. got=%v", tt.expected, err)
+		}
+	}
+}
+
+// letters return a distinct identifier for each i, as identifiers cannot contain digits
+func letters(i int) string {
+	name := ""
+	for ; i >= 0; i = i/26 - 1 {
+		name = string(rune('a'+i%26)) + name
+	}
+	return name
+}
+
+func TestImportIsNotCompiled(t *testing.T) {
+	program := parser.New(lexer.New(`import "lib"`)).ParseProgram()
+
+	err := New().Compile(program)
+	if err == nil || !strings.Contains(err.Error(), "import") {
+		t.Errorf("expected an error about import. got=%v", err)
+	}
+}
+
+func TestQuoteIsNotCompiled(t *testing.T) {
+	program := parser.New(lexer.New("quote(1 + 2)")).ParseProgram()
+
+	err := New().Compile(program)
+	if err == nil || !strings.Contains(err.Error(), "quote") {
+		t.Errorf("expected an error about quote. got=%v", err)
+	}
+}
+
+func compile(t *testing.T, input string) *Bytecode {
+	program := parser.New(lexer.New(input)).ParseProgram()
+
+	compiler := New()
+	if err := compiler.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	return compiler.Bytecode()
+}
@@ -0,0 +1,148 @@
+package compiler
+
+// SymbolScope is where the value of a symbol is stored
+type SymbolScope string
+
+const (
+	GlobalScope  SymbolScope = "GLOBAL"
+	LocalScope   SymbolScope = "LOCAL"
+	BuiltinScope SymbolScope = "BUILTIN"
+	FreeScope    SymbolScope = "FREE"
+)
+
+// Symbol is a name resolved at compile time
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+// SymbolTable is the names defined in a function, or in a block of it which has its own scope.
+// A block table stores its symbols in the slots of the enclosing function,
+// so that a function has one set of locals however its blocks are nested.
+type SymbolTable struct {
+	Outer *SymbolTable
+
+	store          map[string]Symbol
+	numDefinitions int
+	names          []string // names of the definitions, by index
+
+	// FreeSymbols is the symbols of outer functions which the function captures, by index
+	FreeSymbols []Symbol
+
+	block   bool
+	pending map[string]bool // locals hoisted ahead of their let statement, see DefineHoisted
+}
+
+// NewSymbolTable return the table of the global scope
+func NewSymbolTable() *SymbolTable {
+	s := make(map[string]Symbol)
+	return &SymbolTable{store: s, pending: map[string]bool{}}
+}
+
+// NewEnclosedSymbolTable return the table of a function defined in outer
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+// NewBlockSymbolTable return the table of a block scope in outer, such as the variables of a for loop
+func NewBlockSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewEnclosedSymbolTable(outer)
+	s.block = true
+	return s
+}
+
+// owner return the table of the function whose slots the symbols are stored in
+func (s *SymbolTable) owner() *SymbolTable {
+	for s.block {
+		s = s.Outer
+	}
+	return s
+}
+
+// Define name in the table. Defining a name twice in the same scope reuses its slot.
+func (s *SymbolTable) Define(name string) Symbol {
+	delete(s.pending, name)
+	if symbol, ok := s.store[name]; ok && (symbol.Scope == GlobalScope || symbol.Scope == LocalScope) {
+		return symbol
+	}
+
+	owner := s.owner()
+	symbol := Symbol{Name: name, Index: owner.numDefinitions}
+	if owner.Outer == nil {
+		symbol.Scope = GlobalScope
+	} else {
+		symbol.Scope = LocalScope
+	}
+
+	s.store[name] = symbol
+	owner.numDefinitions++
+	owner.names = append(owner.names, name)
+	return symbol
+}
+
+// DefineHoisted define name before its let statement is compiled, so that a function defined earlier can refer to it.
+// Until the let statement, a name hoisted in a function or a block is resolved in the outer scopes by its own function,
+// as `let x = x + 1` reads the outer x, and to its slot by the nested functions, which run later.
+func (s *SymbolTable) DefineHoisted(name string) Symbol {
+	if symbol, ok := s.store[name]; ok && (symbol.Scope == GlobalScope || symbol.Scope == LocalScope) {
+		return symbol
+	}
+
+	symbol := s.Define(name)
+	if s.Outer != nil {
+		s.pending[name] = true
+	}
+	return symbol
+}
+
+// DefineBuiltin define name as the builtin function at index
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{Name: original.Name, Index: len(s.FreeSymbols) - 1, Scope: FreeScope}
+	s.store[original.Name] = symbol
+	return symbol
+}
+
+// Resolve name in the table and its outer tables.
+// A local of an outer function is captured, and resolved as a free symbol.
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	return s.resolve(name, true)
+}
+
+// resolve name, which is looked up by the function of s itself when direct, so that its pending locals are skipped
+func (s *SymbolTable) resolve(name string, direct bool) (Symbol, bool) {
+	symbol, ok := s.store[name]
+	if ok && direct && s.pending[name] {
+		ok = false
+	}
+	if ok || s.Outer == nil {
+		return symbol, ok
+	}
+
+	symbol, ok = s.Outer.resolve(name, direct && s.block)
+	if !ok || s.block {
+		return symbol, ok
+	}
+
+	if symbol.Scope == GlobalScope || symbol.Scope == BuiltinScope {
+		return symbol, ok
+	}
+
+	return s.defineFree(symbol), true
+}
+
+// NumDefinitions return the number of slots the function needs for its definitions
+func (s *SymbolTable) NumDefinitions() int { return s.owner().numDefinitions }
+
+// Names return the names of the definitions of the function, by index
+func (s *SymbolTable) Names() []string { return s.owner().names }
@@ -0,0 +1,110 @@
+package compiler
+
+import "testing"
+
+func TestDefineAndResolve(t *testing.T) {
+	global := NewSymbolTable()
+	a := global.Define("a")
+
+	local := NewEnclosedSymbolTable(global)
+	b := local.Define("b")
+
+	block := NewBlockSymbolTable(local)
+	c := block.Define("c")
+	shadow := block.Define("b")
+
+	expected := []struct {
+		got      Symbol
+		expected Symbol
+	}{
+		{a, Symbol{Name: "a", Scope: GlobalScope, Index: 0}},
+		{b, Symbol{Name: "b", Scope: LocalScope, Index: 0}},
+		{c, Symbol{Name: "c", Scope: LocalScope, Index: 1}},
+		{shadow, Symbol{Name: "b", Scope: LocalScope, Index: 2}},
+		{global.Define("a"), Symbol{Name: "a", Scope: GlobalScope, Index: 0}},
+	}
+
+	for _, tt := range expected {
+		if tt.got != tt.expected {
+			t.Errorf("wrong symbol. expected=%+v, got=%+v", tt.expected, tt.got)
+		}
+	}
+
+	if local.NumDefinitions() != 3 {
+		t.Errorf("block definitions are not counted in the function. got=%d", local.NumDefinitions())
+	}
+	if _, ok := local.Resolve("c"); ok {
+		t.Errorf("block symbol c is visible outside the block")
+	}
+}
+
+func TestResolveFree(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.DefineBuiltin(0, "len")
+
+	outer := NewEnclosedSymbolTable(global)
+	outer.Define("b")
+
+	block := NewBlockSymbolTable(outer)
+	block.Define("c")
+
+	inner := NewEnclosedSymbolTable(block)
+
+	tests := []struct {
+		name     string
+		expected Symbol
+	}{
+		{"a", Symbol{Name: "a", Scope: GlobalScope, Index: 0}},
+		{"len", Symbol{Name: "len", Scope: BuiltinScope, Index: 0}},
+		{"b", Symbol{Name: "b", Scope: FreeScope, Index: 0}},
+		{"c", Symbol{Name: "c", Scope: FreeScope, Index: 1}},
+	}
+
+	for _, tt := range tests {
+		symbol, ok := inner.Resolve(tt.name)
+		if !ok {
+			t.Errorf("name %s not resolvable", tt.name)
+			continue
+		}
+		if symbol != tt.expected {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", tt.name, tt.expected, symbol)
+		}
+	}
+
+	expectedFree := []Symbol{
+		{Name: "b", Scope: LocalScope, Index: 0},
+		{Name: "c", Scope: LocalScope, Index: 1},
+	}
+	if len(inner.FreeSymbols) != len(expectedFree) {
+		t.Fatalf("wrong number of free symbols. got=%d", len(inner.FreeSymbols))
+	}
+	for i, sym := range expectedFree {
+		if inner.FreeSymbols[i] != sym {
+			t.Errorf("wrong free symbol. expected=%+v, got=%+v", sym, inner.FreeSymbols[i])
+		}
+	}
+}
+
+func TestDefineHoisted(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("x")
+
+	local := NewEnclosedSymbolTable(global)
+	hoisted := local.DefineHoisted("x")
+	nested := NewEnclosedSymbolTable(local)
+
+	if s, _ := local.Resolve("x"); s.Scope != GlobalScope {
+		t.Errorf("pending x is not resolved in the outer scope by its function. got=%+v", s)
+	}
+	if s, _ := nested.Resolve("x"); s.Scope != FreeScope {
+		t.Errorf("pending x is not captured by a nested function. got=%+v", s)
+	}
+
+	if defined := local.Define("x"); defined != hoisted {
+		t.Errorf("let statement does not reuse the hoisted slot. expected=%+v, got=%+v", hoisted, defined)
+	}
+	if s, _ := local.Resolve("x"); s != hoisted {
+		t.Errorf("x is not resolved locally after its let statement. got=%+v", s)
+	}
+}
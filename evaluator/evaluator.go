@@ -1,74 +1,176 @@
 package evaluator
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
 	"github.com/NAKKA-K/learn-interpreter-in-go/ast"
 	"github.com/NAKKA-K/learn-interpreter-in-go/object"
 )
 
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL     = object.NULL
+	TRUE     = object.TRUE
+	FALSE    = object.FALSE
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
+// MaxDepth is the default maximum number of nested function calls, deeper recursion is an error instead of a crash
+const MaxDepth = 10000
+
+// evaluator hold the state of one evaluation
+type evaluator struct {
+	depth    int // number of function calls currently being applied
+	maxDepth int
+
+	ctx      context.Context
+	steps    int // number of loop iterations and function calls so far
+	maxSteps int // 0 means no limit
+
+	stdio   *object.IO
+	modules *Modules
+}
+
+// Options configure an evaluation
+type Options struct {
+	MaxSteps int        // number of loop iterations and function calls allowed, 0 means no limit
+	MaxDepth int        // number of nested function calls allowed, MaxDepth when 0
+	IO       *object.IO // where the builtins read and write, the standard streams of the process when nil
+	Modules  *Modules   // the imported files, new ones searching ModulePath for each evaluation when nil
+}
+
+// Eval evaluate all statement and expression
+func Eval(node ast.Node, env *object.Environment) object.Object {
+	return EvalContext(context.Background(), node, env, Options{})
+}
+
+// EvalContext evaluate node like Eval, but stop with an error when ctx is done,
+// or when more than opts.MaxSteps loop iterations and function calls are taken.
+func EvalContext(ctx context.Context, node ast.Node, env *object.Environment, opts Options) object.Object {
+	e := &evaluator{maxDepth: opts.MaxDepth, ctx: ctx, maxSteps: opts.MaxSteps, stdio: opts.IO}
+	if e.maxDepth == 0 {
+		e.maxDepth = MaxDepth
+	}
+	if e.stdio == nil {
+		e.stdio = object.StandardIO()
+	}
+	e.modules = opts.Modules
+	if e.modules == nil {
+		e.modules = NewModules(ModulePath()...)
+	}
+	if program, ok := node.(*ast.Program); ok {
+		return e.evalEntry(program, env)
+	}
+	return e.eval(node, env)
+}
+
+// step count a loop iteration or a function call, and return an error if the evaluation has to stop
+func (e *evaluator) step() *object.Error {
+	select {
+	case <-e.ctx.Done():
+		return &object.Error{Kind: object.CancelledError, Message: "evaluation cancelled: " + e.ctx.Err().Error()}
+	default:
+	}
+
+	e.steps++
+	if e.maxSteps > 0 && e.steps > e.maxSteps {
+		return &object.Error{Kind: object.BudgetExhaustedError, Message: fmt.Sprintf("step budget exhausted after %d steps", e.maxSteps)}
+	}
+	return nil
+}
+
+// isError report whether obj stops the evaluation of the enclosing expression.
+// Besides an error, it is a break or continue raised inside the expression, such as by a branch of an if,
+// which unwinds to the innermost loop in the same way.
 func isError(obj object.Object) bool {
 	if obj != nil {
-		return obj.Type() == object.ERROR_OBJ
+		switch obj.Type() {
+		case object.ERROR_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+			return true
+		}
 	}
 	return false
 }
 
-// Eval evaluate all statement and expression
-func Eval(node ast.Node, env *object.Environment) object.Object {
+func (e *evaluator) eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 
 	// Statements
 	case *ast.Program:
-		return evalProgram(node, env)
+		return e.evalProgram(node, env)
 
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return e.eval(node.Expression, env)
 
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, env)
+		return e.evalBlockStatement(node, env)
 
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
-		if isError(val) {
+		val := e.evalTailExpression(node.ReturnValue, env)
+		switch val.(type) {
+		case *object.Error, *object.ReturnValue, *object.Break, *object.Continue:
+			// raised by the value, or by a branch of an if in tail position
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
+		val := e.eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
+		if fn, ok := val.(*object.Function); ok && fn.Name == "" {
+			fn.Name = node.Name.Value
+		}
 		env.Set(node.Name.Value, val)
 
+	case *ast.WhileStatement:
+		return e.evalWhileStatement(node, env)
+
+	case *ast.ForStatement:
+		return e.evalForStatement(node, env)
+
+	case *ast.ForInStatement:
+		return e.evalForInStatement(node, env)
+
+	case *ast.ImportStatement:
+		return e.evalImportStatement(node, env)
+
+	case *ast.BreakStatement:
+		return BREAK
+
+	case *ast.ContinueStatement:
+		return CONTINUE
+
 	// Expressions
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return e.evalIfExpression(node, env)
 
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
+		left := e.eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		right := Eval(node.Right, env)
+		if node.Operator == "&&" || node.Operator == "||" {
+			return e.evalLogicalExpression(node.Operator, left, node.Right, env)
+		}
+		right := e.eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return withPos(object.InfixOperation(node.Operator, left, right), node)
+
+	case *ast.AssignExpression:
+		return e.evalAssignExpression(node, env)
 
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
+		right := e.eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return withPos(object.PrefixOperation(node.Operator, right), node)
 
 	case *ast.FunctionLiteral:
 		params := node.Parameters
@@ -78,50 +180,66 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.CallExpression:
 		// The quote macro is not evaluated
 		if node.Function.TokenLiteral() == "quote" {
-			return quote(node.Arguments[0], env)
+			return e.quote(node.Arguments[0], env)
 		}
 
-		function := Eval(node.Function, env)
+		function := e.eval(node.Function, env)
 		if isError(function) {
 			return function
 		}
 
-		args := evalExpressions(node.Arguments, env)
+		args := e.evalExpressions(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
 
-		return applyFunction(function, args)
+		if fn, ok := function.(*object.Function); ok {
+			if err := e.checkCall(fn, args); err != nil {
+				return withPos(err, node)
+			}
+		}
+
+		return withFrame(withPos(e.applyFunction(function, args), node), function, node)
 
 	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
+		left := e.eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		index := Eval(node.Index, env)
+		index := e.eval(node.Index, env)
 		if isError(index) {
 			return index
 		}
-		return evalIndexExpression(left, index)
+		return withPos(object.Index(left, index), node)
+
+	case *ast.PropertyExpression:
+		left := e.eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		return withPos(evalPropertyExpression(left, node.Property.Value), node.Property)
 
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return withPos(evalIdentifier(node, env), node)
 
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
 
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 
 	case *ast.ArrayLiteral:
-		elements := evalExpressions(node.Elements, env)
+		elements := e.evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
 		return &object.Array{Elements: elements}
 
 	case *ast.HashLiteral:
-		return evalHashLiteral(node, env)
+		return e.evalHashLiteral(node, env)
 
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
@@ -130,32 +248,38 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	return nil
 }
 
-func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+func (e *evaluator) evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range program.Statements {
-		result = Eval(statement, env)
+		result = e.eval(statement, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
+			if tc, ok := result.Value.(*tailCall); ok {
+				return withFrame(e.callFunction(tc.fn, tc.args), tc.fn, tc.node)
+			}
 			return result.Value
 		case *object.Error:
 			return result
+		case *object.Break, *object.Continue:
+			return withPos(newError("%s outside loop", result.Inspect()), statement)
 		}
 	}
 
 	return result
 }
 
-func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+func (e *evaluator) evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range block.Statements {
-		result = Eval(statement, env)
+		result = e.eval(statement, env)
 
 		if result != nil {
 			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ ||
+				rt == object.BREAK_OBJ || rt == object.CONTINUE_OBJ {
 				return result
 			}
 		}
@@ -164,121 +288,266 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
-func nativeBoolToBooleanObject(input bool) *object.Boolean {
-	if input {
-		return TRUE
+func (e *evaluator) evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := e.eval(ws.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !object.IsTruthy(condition) {
+			return NULL
+		}
+
+		if result, done := e.evalLoopBody(ws.Body, env); done {
+			return result
+		}
 	}
-	return FALSE
 }
 
-func evalInfixExpression(operator string, left, right object.Object) object.Object {
-	switch {
-	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
-		return evalIntegerInfixExpression(operator, left, right)
-	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
-		return evalStringInfixExpression(operator, left, right)
-	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
-	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
-	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
-	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
-	}
-}
-
-func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
-	leftVal := left.(*object.Integer).Value
-	rightVal := right.(*object.Integer).Value
-
-	switch operator {
-	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
-	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
-	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
-	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
-	case "<":
-		return nativeBoolToBooleanObject(leftVal < rightVal)
-	case ">":
-		return nativeBoolToBooleanObject(leftVal > rightVal)
-	case "==":
-		return nativeBoolToBooleanObject(leftVal == rightVal)
-	case "!=":
-		return nativeBoolToBooleanObject(leftVal != rightVal)
-	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+// evalForStatement evaluate a C-style for loop. The variables declared in Init are scoped to the loop.
+func (e *evaluator) evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	loopEnv := object.NewEnclosedEnvironment(env)
+
+	if fs.Init != nil {
+		if init := e.eval(fs.Init, loopEnv); isError(init) {
+			return init
+		}
+	}
+
+	for {
+		if fs.Condition != nil {
+			condition := e.eval(fs.Condition, loopEnv)
+			if isError(condition) {
+				return condition
+			}
+			if !object.IsTruthy(condition) {
+				return NULL
+			}
+		}
+
+		if result, done := e.evalLoopBody(fs.Body, loopEnv); done {
+			return result
+		}
+
+		if fs.Update != nil {
+			if update := e.eval(fs.Update, loopEnv); isError(update) {
+				return update
+			}
+		}
 	}
 }
 
-func evalPrefixExpression(operator string, right object.Object) object.Object {
-	switch operator {
-	case "!":
-		return evalBangOperatorExpression(right)
-	case "-":
-		return evalMinusPrefixOperatorExpression(right)
+// evalForInStatement iterate over the elements of an array, the characters of a string or the keys of a hash
+func (e *evaluator) evalForInStatement(fi *ast.ForInStatement, env *object.Environment) object.Object {
+	iterable := e.eval(fi.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	var next func() (object.Object, bool)
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		next = sliceIterator(iterable.Elements)
+	case *object.String:
+		var chars []object.Object
+		for _, r := range iterable.Value {
+			chars = append(chars, &object.String{Value: string(r)})
+		}
+		next = sliceIterator(chars)
+	case *object.Hash:
+		next = sliceIterator(iterable.Keys())
+	case *object.Iterator:
+		next = iterable.Next
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return withPos(newError("cannot iterate over %s", iterable.Type()), fi.Iterable)
+	}
+
+	loopEnv := object.NewEnclosedEnvironment(env)
+	for {
+		item, ok := next()
+		if !ok {
+			return NULL
+		}
+		if isError(item) {
+			return withPos(item, fi.Iterable)
+		}
+
+		loopEnv.Set(fi.Variable.Value, item)
+
+		if result, done := e.evalLoopBody(fi.Body, loopEnv); done {
+			return result
+		}
 	}
 }
 
-func evalBangOperatorExpression(right object.Object) object.Object {
-	switch right {
-	case TRUE:
-		return FALSE
-	case FALSE:
-		return TRUE
-	case NULL:
-		return TRUE
+// sliceIterator return a function which produces the items one by one, like object.Iterator.Next
+func sliceIterator(items []object.Object) func() (object.Object, bool) {
+	return func() (object.Object, bool) {
+		if len(items) == 0 {
+			return nil, false
+		}
+		item := items[0]
+		items = items[1:]
+		return item, true
+	}
+}
+
+// evalLoopBody evaluate one iteration, and report whether the loop has to stop with result
+func (e *evaluator) evalLoopBody(body *ast.BlockStatement, env *object.Environment) (object.Object, bool) {
+	if err := e.step(); err != nil {
+		return withPos(err, body), true
+	}
+
+	result := e.eval(body, env)
+	if result == nil {
+		return nil, false
+	}
+
+	switch result.Type() {
+	case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+		return result, true
+	case object.BREAK_OBJ:
+		return NULL, true
 	default:
-		return FALSE
+		return nil, false
 	}
 }
 
-func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+func nativeBoolToBooleanObject(input bool) *object.Boolean {
+	if input {
+		return TRUE
 	}
+	return FALSE
+}
 
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+// evalLogicalExpression evaluate "&&" and "||" with short-circuit.
+// The result is the operand which decided the value, so that `x || default` returns x or default.
+func (e *evaluator) evalLogicalExpression(operator string, left object.Object, rightNode ast.Expression, env *object.Environment) object.Object {
+	if operator == "&&" && !object.IsTruthy(left) {
+		return left
+	}
+	if operator == "||" && object.IsTruthy(left) {
+		return left
+	}
+	return e.eval(rightNode, env)
 }
 
-func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
+func (e *evaluator) evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	condition := e.eval(ie.Condition, env)
 	if isError(condition) {
 		return condition
 	}
 
-	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+	if object.IsTruthy(condition) {
+		return e.eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return e.eval(ie.Alternative, env)
 	} else {
 		return NULL
 	}
 }
 
+// evalAssignExpression evaluate "=" and the compound assignments, and return the assigned value
+func (e *evaluator) evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		var current object.Object
+		if node.Operator != "=" {
+			current = evalIdentifier(target, env)
+			if isError(current) {
+				return withPos(current, target)
+			}
+		}
+
+		val := e.evalAssignedValue(node, current, env)
+		if isError(val) {
+			return val
+		}
+
+		if _, ok := env.Assign(target.Value, val); !ok {
+			return withPos(newError("assignment to undeclared identifier: %s", target.Value), target)
+		}
+		return val
+
+	case *ast.IndexExpression:
+		left := e.eval(target.Left, env)
+		if isError(left) {
+			return left
+		}
+		index := e.eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+
+		var current object.Object
+		if node.Operator != "=" {
+			current = object.Index(left, index)
+			if isError(current) {
+				return withPos(current, target)
+			}
+		}
+
+		val := e.evalAssignedValue(node, current, env)
+		if isError(val) {
+			return val
+		}
+
+		return withPos(object.SetIndex(left, index, val), target)
+
+	case *ast.PropertyExpression:
+		left := e.eval(target.Left, env)
+		if isError(left) {
+			return left
+		}
+
+		var current object.Object
+		if node.Operator != "=" {
+			current = evalPropertyExpression(left, target.Property.Value)
+			if isError(current) {
+				return withPos(current, target.Property)
+			}
+		}
+
+		val := e.evalAssignedValue(node, current, env)
+		if isError(val) {
+			return val
+		}
+
+		return withPos(object.SetProperty(left, target.Property.Value, val), target.Property)
+
+	default:
+		return withPos(newError("cannot assign to %s", node.Target.String()), node)
+	}
+}
+
+// evalAssignedValue evaluate the right hand side, and combine it with current for compound assignments
+func (e *evaluator) evalAssignedValue(node *ast.AssignExpression, current object.Object, env *object.Environment) object.Object {
+	val := e.eval(node.Value, env)
+	if isError(val) || node.Operator == "=" {
+		return val
+	}
+
+	operator := strings.TrimSuffix(node.Operator, "=")
+	return withPos(object.InfixOperation(operator, current, val), node)
+}
+
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
 	if val, ok := env.Get(node.Value); ok {
 		return val
 	}
 
-	if builtin, ok := builtins[node.Value]; ok {
+	if builtin := object.GetBuiltinByName(node.Value); builtin != nil {
 		return builtin
 	}
 
 	return newError("identifier not found: " + node.Value)
 }
 
-func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+func (e *evaluator) evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
-	for _, e := range exps {
-		evaluated := Eval(e, env)
+	for _, exp := range exps {
+		evaluated := e.eval(exp, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
@@ -288,54 +557,21 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
-func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
-	if operator != "+" {
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
-	}
-
-	leftVal := left.(*object.String).Value
-	rightVal := right.(*object.String).Value
-	return &object.String{Value: leftVal + rightVal}
-}
-
-func evalIndexExpression(left, index object.Object) object.Object {
-	switch {
-	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
-		return evalArrayIndexExpression(left, index)
-	case left.Type() == object.HASH_OBJ:
-		return evalHashIndexExpression(left, index)
-	default:
-		return newError("index operator not supported: %s", left.Type())
-	}
-}
-
-func evalArrayIndexExpression(array, index object.Object) object.Object {
-	arrayObject := array.(*object.Array)
-	idx := index.(*object.Integer).Value
-	max := int64(len(arrayObject.Elements) - 1)
-
-	if idx < 0 || idx > max {
-		return NULL
-	}
-
-	return arrayObject.Elements[idx]
-}
-
-func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+func (e *evaluator) evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
 	pairs := make(map[object.HashKey]object.HashPair)
 
 	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+		key := e.eval(keyNode, env)
 		if isError(key) {
 			return key
 		}
 
 		hashKey, ok := key.(object.Hashable)
 		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+			return withPos(newError("unusable as hash key: %s", key.Type()), keyNode)
 		}
 
-		value := Eval(valueNode, env)
+		value := e.eval(valueNode, env)
 		if isError(value) {
 			return value
 		}
@@ -347,53 +583,223 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 	return &object.Hash{Pairs: pairs}
 }
 
-func evalHashIndexExpression(hash, index object.Object) object.Object {
-	hashObject := hash.(*object.Hash)
+// evalPropertyExpression return the value of the key name of a hash, or the member name of a module.
+// Unlike an index expression, a missing key is an error.
+func evalPropertyExpression(left object.Object, name string) object.Object {
+	switch left := left.(type) {
+	case *object.Module:
+		return moduleMember(left, name)
+	default:
+		return object.Property(left, name)
+	}
+}
+
+func newError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...)}
+}
 
-	key, ok := index.(object.Hashable)
+// withPos attach the position of node to an error which does not know where it occurred yet
+func withPos(obj object.Object, node ast.Node) object.Object {
+	if err, ok := obj.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+	return obj
+}
+
+// withFrame record the call of fn at node in the stack of an error returned from fn
+func withFrame(obj object.Object, fn object.Object, node *ast.CallExpression) object.Object {
+	err, ok := obj.(*object.Error)
 	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+		return obj
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	function, ok := fn.(*object.Function)
 	if !ok {
-		return NULL
+		return obj
 	}
 
-	return pair.Value
-}
+	err.Stack = append(err.Stack, object.Frame{Function: functionName(function), CallSite: node.Pos()})
 
-func isTruthy(obj object.Object) bool {
-	switch obj {
-	case NULL:
-		return false
-	case FALSE:
-		return false
-	default:
-		return true
-	}
+	return err
 }
 
-func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+// functionName return the name of fn for a stack frame
+func functionName(fn *object.Function) string {
+	if fn.Name == "" {
+		return "<anonymous>"
+	}
+	return fn.Name
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+func (e *evaluator) applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+		return e.callFunction(fn, args)
 
 	case *object.Builtin:
-		return fn.Fn(args...)
+		return fn.Fn(e.stdio, args...)
 
 	default:
 		return newError("not a function: %s", fn.Type())
 	}
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+// maxTailFrames is the number of tail calls kept for the stack of an error
+const maxTailFrames = 100
+
+// callFunction apply fn to args. A call in tail position of fn is returned as a tailCall,
+// and applied by this loop instead of a nested Eval, so that tail recursion does not grow the Go stack.
+func (e *evaluator) callFunction(fn *object.Function, args []object.Object) object.Object {
+	e.depth++
+	defer func() { e.depth-- }()
+
+	var tailFrames []object.Frame
+
+	for {
+		if err := e.step(); err != nil {
+			return err
+		}
+
+		extendedEnv := e.extendFunctionEnv(fn, args)
+		evaluated := unwrapReturnValue(e.evalFunctionBody(fn.Body, extendedEnv))
+
+		tc, ok := evaluated.(*tailCall)
+		if !ok {
+			if err, ok := evaluated.(*object.Error); ok {
+				// The tail calls replaced each other, but they are reported as if they had been nested
+				for i := len(tailFrames) - 1; i >= 0; i-- {
+					err.Stack = append(err.Stack, tailFrames[i])
+				}
+			}
+			return evaluated
+		}
+
+		tailFrames = append(tailFrames, object.Frame{Function: functionName(tc.fn), CallSite: tc.node.Pos()})
+		if len(tailFrames) > 2*maxTailFrames {
+			tailFrames = append(tailFrames[:0], tailFrames[len(tailFrames)-maxTailFrames:]...)
+		}
+
+		fn, args = tc.fn, tc.args
+	}
+}
+
+// tailCall is a call in tail position, which is applied by the trampoline of callFunction
+type tailCall struct {
+	fn   *object.Function
+	args []object.Object
+	node *ast.CallExpression
+}
+
+func (tc *tailCall) Inspect() string         { return "tail call of " + functionName(tc.fn) }
+func (tc *tailCall) Type() object.ObjectType { return "TAIL_CALL" }
+
+// evalFunctionBody evaluate the body of a function, with its last statement in tail position
+func (e *evaluator) evalFunctionBody(block *ast.BlockStatement, env *object.Environment) object.Object {
+	var result object.Object
+
+	for i, statement := range block.Statements {
+		if i == len(block.Statements)-1 {
+			return e.evalTailStatement(statement, env)
+		}
+
+		result = e.eval(statement, env)
+
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ ||
+				rt == object.BREAK_OBJ || rt == object.CONTINUE_OBJ {
+				return result
+			}
+		}
+	}
+
+	return result
+}
+
+// evalTailStatement evaluate a statement in tail position, see evalTailExpression
+func (e *evaluator) evalTailStatement(statement ast.Statement, env *object.Environment) object.Object {
+	es, ok := statement.(*ast.ExpressionStatement)
+	if !ok {
+		return e.eval(statement, env)
+	}
+	return e.evalTailExpression(es.Expression, env)
+}
+
+// evalTailExpression evaluate an expression in tail position, such as the value of a return.
+// A call is not applied but returned as a tailCall, and the branches of an if are in tail position too.
+func (e *evaluator) evalTailExpression(exp ast.Expression, env *object.Environment) object.Object {
+	switch exp := exp.(type) {
+	case *ast.CallExpression:
+		return e.evalTailCall(exp, env)
+
+	case *ast.IfExpression:
+		condition := e.eval(exp.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+
+		if object.IsTruthy(condition) {
+			return e.evalFunctionBody(exp.Consequence, env)
+		} else if exp.Alternative != nil {
+			return e.evalFunctionBody(exp.Alternative, env)
+		} else {
+			return NULL
+		}
+
+	default:
+		return e.eval(exp, env)
+	}
+}
+
+// evalTailCall evaluate the function and the arguments of a call in tail position.
+// A call of a Function is returned as a tailCall; any other call is applied at once.
+func (e *evaluator) evalTailCall(node *ast.CallExpression, env *object.Environment) object.Object {
+	if node.Function.TokenLiteral() == "quote" {
+		return e.eval(node, env)
+	}
+
+	function := e.eval(node.Function, env)
+	if isError(function) {
+		return function
+	}
+
+	args := e.evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	if fn, ok := function.(*object.Function); ok {
+		if err := checkArguments(fn, args); err != nil {
+			return withPos(err, node)
+		}
+		return &tailCall{fn: fn, args: args, node: node}
+	}
+
+	return withPos(e.applyFunction(function, args), node)
+}
+
+// checkArguments return an error unless args are as many as the parameters of fn.
+// It is checked at the call site, so the error is not in the stack of fn.
+func checkArguments(fn *object.Function, args []object.Object) *object.Error {
+	if len(args) != len(fn.Parameters) {
+		return newError("wrong number of arguments: want=%d, got=%d", len(fn.Parameters), len(args))
+	}
+	return nil
+}
+
+// checkCall return an error unless fn can be called with args without exceeding the maximum depth.
+// A tail call replaces its caller, so only checkArguments applies to it.
+func (e *evaluator) checkCall(fn *object.Function, args []object.Object) *object.Error {
+	if err := checkArguments(fn, args); err != nil {
+		return err
+	}
+	if e.depth >= e.maxDepth {
+		return newError("maximum recursion depth exceeded in %s", functionName(fn))
+	}
+	return nil
+}
+
+func (e *evaluator) extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
 	env := object.NewEnclosedEnvironment(fn.Env)
 
 	for paramIdx, param := range fn.Parameters {
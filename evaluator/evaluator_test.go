@@ -1,7 +1,11 @@
 package evaluator
 
 import (
+	"bytes"
+	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/NAKKA-K/learn-interpreter-in-go/lexer"
 	"github.com/NAKKA-K/learn-interpreter-in-go/object"
@@ -29,6 +33,9 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 + 10 % 4 * 3", 8},
 	}
 
 	for _, tt := range tests {
@@ -37,6 +44,71 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.5", 3.5},
+		{"-2.5", -2.5},
+		{"1e-3", 0.001},
+		{"0.1 + 0.2 * 2", 0.5},
+		{"7.0 / 2", 3.5},
+		{"7 / 2.0", 3.5},
+		{"1 + 0.5", 1.5},
+		{"2.5 * 2", 5},
+		{"(1 + 2) / 4.0", 0.75},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testFloatObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`5 || 10`, "5"},
+		{`false || "default"`, "default"},
+		{`let x = if (false) { 1 }; x || "fallback"`, "fallback"},
+		{`0 && 10`, "10"},
+		{`false && undefinedName`, "false"},
+		{`true || undefinedName`, "true"},
+		{`true && undefinedName`, "ERROR: 1:9: identifier not found: undefinedName"},
+		{`let n = 0; let inc = fn() { n += 1; true }; false && inc(); true || inc(); n`, "0"},
+		{`5.5 % 2`, "1.5"},
+		{`5 % 0`, "ERROR: 1:1: division by zero: 5 % 0"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestMixedNumberComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1 == 1.0", true},
+		{"1.0 != 1", false},
+		{"1 < 1.5", true},
+		{"2.5 > 3", false},
+		{"0.1 + 0.2 == 0.3", false},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -61,6 +133,18 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"1.5 <= 1", false},
+		{"2 >= 1.5", true},
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
 	}
 
 	for _, tt := range tests {
@@ -97,6 +181,11 @@ func testEval(input string) object.Object {
 	return Eval(program, env)
 }
 
+func testEvalOptions(input string, opts Options) object.Object {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	return EvalContext(context.Background(), program, object.NewEnvironment(), opts)
+}
+
 func TestIfElseExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -177,6 +266,14 @@ func TestErrorHandling(t *testing.T) {
 			"if (10 > 1) { true + false; }",
 			"unknown operator: BOOLEAN + BOOLEAN",
 		},
+		{
+			"let f = fn(a, b) { b }; f(1)",
+			"wrong number of arguments: want=2, got=1",
+		},
+		{
+			"let f = fn(a) { a }; let g = fn() { f(1, 2) }; g()",
+			"wrong number of arguments: want=1, got=2",
+		},
 		{
 			`
 			if (10 > 1) {
@@ -199,6 +296,14 @@ func TestErrorHandling(t *testing.T) {
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			"10 / 0",
+			"division by zero: 10 / 0",
+		},
+		{
+			"1.5 + true",
+			"type mismatch: FLOAT + BOOLEAN",
+		},
 	}
 
 	for _, tt := range tests {
@@ -216,6 +321,202 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+func TestErrorPosition(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedInspect string
+	}{
+		{"foobar", "ERROR: 1:1: identifier not found: foobar"},
+		{"let x = 5;\nx + true;", "ERROR: 2:1: type mismatch: INTEGER + BOOLEAN"},
+		{"let f = fn() {\n  -true\n};\nf();", "ERROR: 2:3: unknown operator: -BOOLEAN"},
+		{"len(1)", "ERROR: 1:1: argument to `len` not supported, got INTEGER"},
+		{`let user = {"name": "Ann"};` + "\nuser.age", `ERROR: 2:6: hash has no key "age"`},
+		{"let x = 5; x.y", "ERROR: 1:14: property access not supported: INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Inspect() != tt.expectedInspect {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedInspect, errObj.Inspect())
+		}
+	}
+}
+
+func TestPropertyExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let user = {"name": "Ann", "age": 30}; user.age`, 30},
+		{`let user = {"address": {"zip": 75001}}; user.address.zip`, 75001},
+		{`let user = {"age": 30, "older": fn(n) { n + 1 }}; user.older(user.age)`, 31},
+		{`{"n": 1, 1: 2}.n`, 1},
+		{`{"x": if (false) { 1 }}.x`, nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (i < 5) { let i = i + 1; }; i", 5},
+		{"let i = 0; while (true) { let i = i + 1; if (i == 3) { break; } }; i", 3},
+		{"let i = 0; let n = 0; while (i < 5) { let i = i + 1; if (i == 2) { continue; } let n = n + 1; }; n", 4},
+		{"let f = fn() { let i = 0; while (true) { let i = i + 1; if (i > 2) { return i * 10; } } }; f()", 30},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x; } } }; f()", 2},
+		{"for (let i = 0; i < 3; let i = i + 1) { puts(i) }", nil},
+		{"let f = fn() { for (let i = 0; ; let i = i + 1) { if (i == 7) { return i; } } }; f()", 7},
+		{"let f = fn() { let last = 0; for (x in [1, 2, 3]) { let last = x; } }; f()", nil},
+		{"while (false) { 1 }", nil},
+		{"let i = 0; while (i < 100000) { let i = i + 1; }; i", 100000},
+		{"let i = 0; while (true) { let i = i + 1; let x = if (i == 3) { break } }; i", 3},
+		{"let n = 0; for (x in [1, 2, 3]) { n = n + [x, if (x == 2) { continue } else { 0 }][0] }; n", 4},
+		{"let i = 0; while (true) { let i = i + 1; [if (i < 3) { i } else { break }] }; i", 3},
+		{"let i = 0; while (true) { let i = i + 1; i > 2 && if (true) { break } }; i", 3},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestForInIterables(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let f = fn(xs) { for (x in xs) { if (len(x) > 1) { return x; } } }; f(["a", "bc"])`, "bc"},
+		{`let f = fn(h) { for (k in h) { return k; } }; f({"b": 1, "a": 2})`, "a"},
+		{`let f = fn(s) { for (c in s) { return c; } }; f("日本")`, "日"},
+		{`for (x in [1]) { x }; x`, "ERROR: 1:23: identifier not found: x"},
+		{`for (x in 5) { x }`, "ERROR: 1:11: cannot iterate over INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let x = 1; x = 5; x", 5},
+		{"let x = 1; x = 5", 5},
+		{"let x = 1; let y = 2; x = y = 3; x + y", 6},
+		{"let x = 10; x += 5; x", 15},
+		{"let x = 10; x -= 5; x", 5},
+		{"let x = 10; x *= 5; x", 50},
+		{"let x = 10; x /= 5; x", 2},
+		{"let counter = fn() { let n = 0; fn() { n += 1 } }; let c = counter(); c(); c(); c()", 3},
+		{"let n = 0; let inc = fn() { n = n + 1; }; inc(); inc(); n", 2},
+		{"let n = 0; let f = fn(n) { n = 5; }; f(1); n", 0},
+		{"let total = 0; for (let i = 0; i < 5; i += 1) { total += i; }; total", 10},
+		{"let total = 0; for (x in [1, 2, 3]) { total = total + x; }; total", 6},
+		{"let a = [1, 2, 3]; a[1] = 20; a[1]", 20},
+		{"let a = [1, 2, 3]; a[2] *= 10; a[2]", 30},
+		{`let h = {"a": 1}; h["a"] += 1; h["a"]`, 2},
+		{`let h = {}; h["b"] = 7; h["b"]`, 7},
+		{`let h = {"xs": [1]}; h["xs"][0] = 9; h["xs"][0]`, 9},
+		{`let h = {"n": 1}; h.n = 5; h.n`, 5},
+		{`let h = {"n": 1}; h.n += 2; h["n"]`, 3},
+		{`let h = {}; h.n = 7; h.n`, 7},
+		{`let h = {"a": {"n": 1}}; h.a.n *= 4; h.a.n`, 4},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), int64(tt.expected.(int)))
+	}
+}
+
+func TestAssignErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"x = 1", "assignment to undeclared identifier: x"},
+		{"x += 1", "identifier not found: x"},
+		{"let a = [1]; a[1] = 2", "index out of range: 1"},
+		{`let a = [1]; a["x"] = 2`, "array index must be INTEGER, got STRING"},
+		{`let s = "abc"; s[0] = "x"`, "index assignment not supported: STRING"},
+		{`let h = {}; h[fn() {}] = 1`, "unusable as hash key: FUNCTION"},
+		{`let x = 1; x += "a"`, "type mismatch: INTEGER + STRING"},
+		{`let h = {}; h.n += 1`, `hash has no key "n"`},
+		{`let x = 1; x.y = 2`, "property assignment not supported: INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestErrorStackTrace(t *testing.T) {
+	input := `let inner = fn(x) {
+  x + true
+};
+let outer = fn(x) { inner(x) };
+fn() { outer(1) }();`
+
+	evaluated := testEval(input)
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	expected := []string{"inner called at 4:21", "outer called at 5:8", "<anonymous> called at 5:1"}
+	if len(errObj.Stack) != len(expected) {
+		t.Fatalf("wrong stack size. expected=%d, got=%d (%v)", len(expected), len(errObj.Stack), errObj.Stack)
+	}
+	for i, frame := range expected {
+		if errObj.Stack[i].String() != frame {
+			t.Errorf("wrong frame %d. expected=%q, got=%q", i, frame, errObj.Stack[i].String())
+		}
+	}
+
+	if errObj.Pos.String() != "2:3" {
+		t.Errorf("wrong error position. expected=2:3, got=%s", errObj.Pos)
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -285,6 +586,134 @@ func TestClosures(t *testing.T) {
 	testIntegerObject(t, testEval(input), 4)
 }
 
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let countdown = fn(n) { if (n == 0) { 0 } else { countdown(n - 1) } }; countdown(1000000)", 0},
+		{"let sum = fn(n, acc) { if (n == 0) { return acc; } return sum(n - 1, acc + n); }; sum(1000000, 0)", 500000500000},
+		{`
+			let isEven = fn(n) { if (n == 0) { 1 } else { isOdd(n - 1) } };
+			let isOdd = fn(n) { if (n == 0) { 0 } else { isEven(n - 1) } };
+			isEven(1000001)`,
+			0,
+		},
+		{"let count = fn(n) { if (n > 0) { return count(n - 1); } len([n]) }; count(1000000)", 1},
+		{"let f = fn(n) { return if (n == 0) { 0 } else { f(n - 1) } }; f(1000000)", 0},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	evaluated := testEval("let f = fn(n) { 1 + f(n + 1) }; f(0)")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "maximum recursion depth exceeded in f" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+	if errObj.Pos.String() != "1:21" {
+		t.Errorf("wrong error position. got=%s", errObj.Pos)
+	}
+
+	opts := Options{MaxDepth: 3}
+	testIntegerObject(t, testEvalOptions("let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(2)", opts), 2)
+	if _, ok := testEvalOptions("let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(3)", opts).(*object.Error); !ok {
+		t.Errorf("recursion deeper than Options.MaxDepth is not an error")
+	}
+}
+
+func TestEvalContext(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	timeout, cancelTimeout := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelTimeout()
+
+	tests := []struct {
+		input    string
+		ctx      context.Context
+		maxSteps int
+		kind     object.ErrorKind
+		message  string
+	}{
+		{"while (true) { }", context.Background(), 100, object.BudgetExhaustedError, "step budget exhausted after 100 steps"},
+		{"let f = fn() { f() }; f()", context.Background(), 100, object.BudgetExhaustedError, "step budget exhausted after 100 steps"},
+		{"for (x in [1, 2]) { }", cancelled, 0, object.CancelledError, "evaluation cancelled: context canceled"},
+		{"while (true) { }", timeout, 0, object.CancelledError, "evaluation cancelled: context deadline exceeded"},
+		{"let x = fn(y) { y }(1); x + true", context.Background(), 100, object.RuntimeError, "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		evaluated := EvalContext(tt.ctx, program, object.NewEnvironment(), Options{MaxSteps: tt.maxSteps})
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != tt.kind || errObj.Message != tt.message {
+			t.Errorf("wrong error for %q. expected=%d %q, got=%d %q", tt.input, tt.kind, tt.message, errObj.Kind, errObj.Message)
+		}
+	}
+
+	program := parser.New(lexer.New("let i = 0; while (i < 10) { i += 1 }; i")).ParseProgram()
+	testIntegerObject(t, EvalContext(context.Background(), program, object.NewEnvironment(), Options{MaxSteps: 10}), 10)
+}
+
+func TestBuiltinOutput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	stdio := &object.IO{Stdout: &stdout, Stderr: &stderr}
+
+	// eprint formats like print and differs only in writing to stderr
+	input := `puts(1, "a"); print("b", 2); print("c"); eprint("oops", 1); eprint("!")`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	EvalContext(context.Background(), program, object.NewEnvironment(), Options{IO: stdio})
+
+	if stdout.String() != "1\na\nb 2c" {
+		t.Errorf("wrong stdout. got=%q", stdout.String())
+	}
+	if stderr.String() != "oops 1!" {
+		t.Errorf("wrong stderr. got=%q", stderr.String())
+	}
+}
+
+func TestBuiltinInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		stdin    string
+		expected string
+		stdout   string
+	}{
+		{`input("name? ")`, "monkey\nrest\n", "monkey", "name? "},
+		{`[read_line(), read_line(), read_line()]`, "a\r\nb", "[a, b, null]", ""},
+		{`read_line(); read_all()`, "a\nb\nc\n", "b\nc\n", ""},
+		{`let n = 0; for (line in lines()) { n += len(line) }; n`, "ab\ncd\nef", "6", ""},
+		{`for (line in lines()) { print(line) }; read_line()`, "1\n2\n", "null", "12"},
+		{`read_line(1)`, "", "ERROR: 1:1: wrong number of arguments. got=1, want=0", ""},
+	}
+
+	for _, tt := range tests {
+		var stdout bytes.Buffer
+		stdio := &object.IO{Stdin: strings.NewReader(tt.stdin), Stdout: &stdout}
+
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		evaluated := EvalContext(context.Background(), program, object.NewEnvironment(), Options{IO: stdio})
+
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+		if stdout.String() != tt.stdout {
+			t.Errorf("wrong stdout for %q. expected=%q, got=%q", tt.input, tt.stdout, stdout.String())
+		}
+	}
+}
+
 func TestStringLiteral(t *testing.T) {
 	input := `"Hello World!"`
 
@@ -321,6 +750,8 @@ func TestBuiltinFunction(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("héllo")`, 5},
+		{`len("\u{1F600}!")`, 2},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
 		{`len([1, 2, 3])`, 3},
@@ -406,6 +837,38 @@ func TestArrayLiterals(t *testing.T) {
 	testIntegerObject(t, result.Elements[2], 6)
 }
 
+func TestStringIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"abc"[0]`, "a"},
+		{`"héllo"[1]`, "é"},
+		{`"héllo"[4]`, "o"},
+		{`let s = "日本語"; s[len(s) - 1]`, "語"},
+		{`"abc"[3]`, nil},
+		{`"abc"[-1]`, nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		expected, ok := tt.expected.(string)
+		if !ok {
+			testNullObject(t, evaluated)
+			continue
+		}
+
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != expected {
+			t.Errorf("String has wrong value. got=%q, want=%q", str.Value, expected)
+		}
+	}
+}
+
 func TestArrayIndexExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -537,6 +1000,22 @@ func TestHashIndexExpressions(t *testing.T) {
 			`{false: 5}[false]`,
 			5,
 		},
+		{
+			`{1.5: 5}[1.5]`,
+			5,
+		},
+		{
+			`{1: 5}[1.0]`,
+			5,
+		},
+		{
+			`{2.0: 5}[2]`,
+			5,
+		},
+		{
+			`{1.5: 5}[1]`,
+			nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -573,6 +1052,20 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
 func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 	result, ok := obj.(*object.Boolean)
 	if !ok {
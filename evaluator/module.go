@@ -0,0 +1,201 @@
+package evaluator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NAKKA-K/learn-interpreter-in-go/ast"
+	"github.com/NAKKA-K/learn-interpreter-in-go/lexer"
+	"github.com/NAKKA-K/learn-interpreter-in-go/object"
+	"github.com/NAKKA-K/learn-interpreter-in-go/parser"
+)
+
+// ModuleExt is the extension added to an import path which is not found as it is
+const ModuleExt = ".mk"
+
+// Modules load the files imported by programs, and keep them so that each file is evaluated once
+type Modules struct {
+	// Path is the directories searched for an import which is not found next to the importing file.
+	// An import path starting with "./" or "../" is only searched next to the importing file.
+	Path []string
+
+	// Builtins encloses the environment of every module, unless it is nil
+	Builtins *object.Environment
+
+	cache   map[string]*object.Module // by absolute path
+	loading []string                  // the files being imported, the innermost last
+}
+
+// NewModules return Modules searching path
+func NewModules(path ...string) *Modules {
+	return &Modules{Path: path, cache: map[string]*object.Module{}}
+}
+
+// ModulePath return the directories listed by the environment variable MONKEYPATH, like PATH
+func ModulePath() []string {
+	return filepath.SplitList(os.Getenv("MONKEYPATH"))
+}
+
+// resolve return the absolute path of the file imported as path by the file from
+func (m *Modules) resolve(path, from string) (string, error) {
+	dir := "."
+	if from != "" && !strings.HasPrefix(from, "<") { // "<stdin>" or "<cmdline>" are not files
+		dir = filepath.Dir(from)
+	}
+
+	var candidates []string
+	switch {
+	case filepath.IsAbs(path):
+		candidates = []string{path}
+	case strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../"):
+		candidates = []string{filepath.Join(dir, path)}
+	default:
+		candidates = []string{filepath.Join(dir, path)}
+		for _, p := range m.Path {
+			candidates = append(candidates, filepath.Join(p, path))
+		}
+	}
+
+	for _, c := range candidates {
+		for _, file := range []string{c, c + ModuleExt} {
+			if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() {
+				return filepath.Abs(file)
+			}
+		}
+	}
+	return "", fmt.Errorf("cannot find module %q", path)
+}
+
+// moduleName return the name a module imported as path is bound to: "lib/math.mk" is "math"
+func moduleName(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// evalImportStatement bind the module of the imported file, or the exported names it lists,
+// evaluating the file on its first import
+func (e *evaluator) evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+	obj := e.importModule(node)
+	if isError(obj) {
+		return withPos(obj, node)
+	}
+	module := obj.(*object.Module)
+
+	if len(node.Names) > 0 {
+		for _, name := range node.Names {
+			value := moduleMember(module, name.Value)
+			if isError(value) {
+				return withPos(value, name)
+			}
+			env.Set(name.Value, value)
+		}
+		return nil
+	}
+
+	name := moduleName(node.Path.Value)
+	if node.Alias != nil {
+		name = node.Alias.Value
+	}
+	env.Set(name, module)
+	return nil
+}
+
+func (e *evaluator) importModule(node *ast.ImportStatement) object.Object {
+	m := e.modules
+
+	path, err := m.resolve(node.Path.Value, node.Pos().Filename)
+	if err != nil {
+		return newError("%s", err)
+	}
+	if module, ok := m.cache[path]; ok {
+		return module
+	}
+
+	for i, loading := range m.loading {
+		if loading == path {
+			cycle := []string{}
+			for _, p := range m.loading[i:] {
+				cycle = append(cycle, filepath.Base(p))
+			}
+			cycle = append(cycle, filepath.Base(path))
+			return newError("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return newError("cannot import %q: %s", node.Path.Value, err)
+	}
+
+	p := parser.New(lexer.NewFile(path, string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return newError("cannot import %q: %s", node.Path.Value, p.Errors()[0])
+	}
+
+	macroEnv := object.NewEnvironment()
+	DefineMacros(program, macroEnv)
+	expanded := ExpandMacros(program, macroEnv)
+
+	env := object.NewEnvironment()
+	if m.Builtins != nil {
+		env = object.NewEnclosedEnvironment(m.Builtins)
+	}
+
+	m.loading = append(m.loading, path)
+	result := e.evalModule(expanded, env)
+	if isError(result) {
+		return result
+	}
+
+	module := &object.Module{Name: moduleName(path), Path: path, Env: env, Exports: exports(program)}
+	m.cache[path] = module
+	return module
+}
+
+// evalEntry evaluate the program being run. When it was read from a file, the file is loading
+// while it runs, so that an import cycle through it is reported from it instead of importing it again.
+func (e *evaluator) evalEntry(program *ast.Program, env *object.Environment) object.Object {
+	filename := program.Pos().Filename
+	if filename == "" || strings.HasPrefix(filename, "<") { // "<stdin>" or "<cmdline>" are not files
+		return e.eval(program, env)
+	}
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return e.eval(program, env)
+	}
+
+	e.modules.loading = append(e.modules.loading, path)
+	return e.evalModule(program, env)
+}
+
+// evalModule evaluate the program of the innermost loading module, which is popped even on a panic
+func (e *evaluator) evalModule(program ast.Node, env *object.Environment) object.Object {
+	defer func() { e.modules.loading = e.modules.loading[:len(e.modules.loading)-1] }()
+	return e.eval(program, env)
+}
+
+// exports return the names bound by the "export let" statements of program
+func exports(program *ast.Program) map[string]bool {
+	names := map[string]bool{}
+	for _, stmt := range program.Statements {
+		if let, ok := stmt.(*ast.LetStatement); ok && let.Exported {
+			names[let.Name.Value] = true
+		}
+	}
+	return names
+}
+
+// moduleMember return the exported binding name of module
+func moduleMember(module *object.Module, name string) object.Object {
+	if value, ok := module.Get(name); ok {
+		return value
+	}
+	if module.Private(name) {
+		return newError("%s is not exported by module %s", name, module.Name)
+	}
+	return newError("module %s has no member %s", module.Name, name)
+}
@@ -0,0 +1,191 @@
+package evaluator
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NAKKA-K/learn-interpreter-in-go/lexer"
+	"github.com/NAKKA-K/learn-interpreter-in-go/object"
+	"github.com/NAKKA-K/learn-interpreter-in-go/parser"
+)
+
+// writeFiles create the files of a directory tree, by path relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// testEvalFile evaluate input as if it was read from filename
+func testEvalFile(filename, input string, opts Options) object.Object {
+	p := parser.New(lexer.NewFile(filename, input))
+	program := p.ParseProgram()
+	return EvalContext(context.Background(), program, object.NewEnvironment(), opts)
+}
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"lib/math.mk":   `import "./square" as sqr; export let square = sqr; export let double = fn(x) { x * 2 }; export let quad = fn(x) { double(double(x)) };`,
+		"lib/square.mk": `export let sq = fn(x) { x * x };`,
+		"counter.mk":    `puts("loaded"); export let n = 1;`,
+		"search/util":   `export let answer = 42; let secret = 0;`,
+	})
+	main := filepath.Join(dir, "main.mk")
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`import "lib/math"; math.double(5)`, 10},
+		{`import "lib/math.mk"; math.quad(3)`, 12},
+		{`import "lib/math"; math.square.sq(4)`, 16},
+		{`import "./counter"; counter.n`, 1},
+		{`import "util"; util.answer`, 42},
+		{`import "lib/math"; math.sq`, "module math has no member sq"},
+		{`import "lib/math"; math.sqr`, "sqr is not exported by module math"},
+		{`import "util"; util.secret`, "secret is not exported by module util"},
+		{`import "lib/math" as m; m.double(4)`, 8},
+		{`import { double, quad } from "lib/math"; double(1) + quad(1)`, 6},
+		{`import { answer } from "util"; answer`, 42},
+		{`import { secret } from "util"`, "secret is not exported by module util"},
+		{`import { nothing } from "util"`, "module util has no member nothing"},
+		{`export let x = 5; x`, 5},
+		{`import "missing"`, `cannot find module "missing"`},
+		{`let h = 1; h.x`, "property access not supported: INTEGER"},
+	}
+
+	for _, tt := range tests {
+		modules := NewModules(filepath.Join(dir, "search"))
+		evaluated := testEvalFile(main, tt.input, Options{IO: &object.IO{Stdout: ioutil.Discard}, Modules: modules})
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestImportIsCached(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"counter.mk": `puts("loaded"); export let n = 1;`,
+		"other.mk":   `import "counter"; export let n = counter.n + 1;`,
+	})
+
+	var out bytes.Buffer
+	opts := Options{IO: &object.IO{Stdout: &out}, Modules: NewModules()}
+	input := `import "counter"; import "other"; import "counter"; counter.n + other.n`
+
+	evaluated := testEvalFile(filepath.Join(dir, "main.mk"), input, opts)
+	testIntegerObject(t, evaluated, 3)
+	if out.String() != "loaded\n" {
+		t.Errorf("module is not evaluated once. output=%q", out.String())
+	}
+}
+
+func TestImportWithoutModules(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "answer.mk")
+	input := `import "` + path + `" as a; a.n`
+
+	writeFiles(t, dir, map[string]string{"answer.mk": `export let n = 1;`})
+	testIntegerObject(t, testEvalFile("<stdin>", input, Options{}), 1)
+
+	// Each evaluation without Options.Modules loads the modules again
+	writeFiles(t, dir, map[string]string{"answer.mk": `export let n = 2;`})
+	testIntegerObject(t, testEvalFile("<stdin>", input, Options{}), 2)
+}
+
+func TestImportErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.mk":      `import "b";`,
+		"b.mk":      `import "a";`,
+		"broken.mk": `let = 1;`,
+		"failing.mk": `let x = 1;
+1 + true;`,
+	})
+	main := filepath.Join(dir, "main.mk")
+
+	tests := []struct {
+		input    string
+		expected string
+		pos      string
+	}{
+		{`import "a"`, "import cycle: a.mk -> b.mk -> a.mk", filepath.Join(dir, "b.mk") + ":1:1"},
+		{`import "broken"`, `cannot import "broken": ` + filepath.Join(dir, "broken.mk") + ":1:5: expected next token to be IDENT, got = insted", main + ":1:1"},
+		{`import "failing"`, "type mismatch: INTEGER + BOOLEAN", filepath.Join(dir, "failing.mk") + ":2:1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalFile(main, tt.input, Options{Modules: NewModules()})
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		}
+		if errObj.Pos.String() != tt.pos {
+			t.Errorf("wrong error position for %q. expected=%q, got=%q", tt.input, tt.pos, errObj.Pos)
+		}
+	}
+}
+
+func TestImportCycleThroughEntry(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.mk": `puts("a"); import "b";`,
+		"b.mk": `import "a";`,
+	})
+
+	var out bytes.Buffer
+	evaluated := testEvalFile(filepath.Join(dir, "a.mk"), `puts("a"); import "b";`, Options{IO: &object.IO{Stdout: &out}, Modules: NewModules()})
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "import cycle: a.mk -> b.mk -> a.mk" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+	if out.String() != "a\n" {
+		t.Errorf("entry file is evaluated again as a module. output=%q", out.String())
+	}
+}
+
+func TestModuleName(t *testing.T) {
+	for path, expected := range map[string]string{
+		"lib":             "lib",
+		"path/to/lib":     "lib",
+		"./lib/math.mk":   "math",
+		"../strings.util": "strings",
+	} {
+		if name := moduleName(path); name != expected {
+			t.Errorf("wrong name for %q. expected=%q, got=%q", path, expected, name)
+		}
+	}
+}
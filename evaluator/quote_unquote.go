@@ -8,12 +8,12 @@ import (
 	"github.com/NAKKA-K/learn-interpreter-in-go/token"
 )
 
-func quote(node ast.Node, env *object.Environment) object.Object {
-	node = evalUnquoteCalls(node, env)
+func (e *evaluator) quote(node ast.Node, env *object.Environment) object.Object {
+	node = e.evalUnquoteCalls(node, env)
 	return &object.Quote{Node: node}
 }
 
-func evalUnquoteCalls(quoted ast.Node, env *object.Environment) ast.Node {
+func (e *evaluator) evalUnquoteCalls(quoted ast.Node, env *object.Environment) ast.Node {
 	return ast.Modify(quoted, func(node ast.Node) ast.Node {
 		if !isUnquoteCall(node) {
 			return node
@@ -28,7 +28,7 @@ func evalUnquoteCalls(quoted ast.Node, env *object.Environment) ast.Node {
 			return node
 		}
 
-		unquoted := Eval(call.Arguments[0], env)
+		unquoted := e.eval(call.Arguments[0], env)
 		return convertObjectToASTNodde(unquoted)
 	})
 }
@@ -42,6 +42,13 @@ func convertObjectToASTNodde(obj object.Object) ast.Node {
 		}
 		return &ast.IntegerLiteral{Token: t, Value: obj.Value}
 
+	case *object.Float:
+		t := token.Token{
+			Type:    token.FLOAT,
+			Literal: obj.Inspect(),
+		}
+		return &ast.FloatLiteral{Token: t, Value: obj.Value}
+
 	case *object.Boolean:
 		var t token.Token
 		if obj.Value {
@@ -0,0 +1,443 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/NAKKA-K/learn-interpreter-in-go/object"
+)
+
+var (
+	objectType = reflect.TypeOf((*object.Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+	ioType     = reflect.TypeOf((*object.IO)(nil))
+)
+
+// ToObject convert a Go value to an object.
+// Numbers, strings and bools become INTEGER, FLOAT, STRING and BOOLEAN, nil becomes NULL,
+// slices and arrays become ARRAY, maps and structs become HASH, and functions become builtins (see Wrap).
+// The fields of a struct are keyed by their name, or by the name in a `monkey:"name"` tag. A field tagged "-" is skipped.
+func ToObject(v interface{}) (object.Object, error) {
+	return toObject(reflect.ValueOf(v))
+}
+
+func toObject(v reflect.Value) (object.Object, error) {
+	if !v.IsValid() {
+		return object.NULL, nil
+	}
+	if obj, ok := v.Interface().(object.Object); ok {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return object.NULL, nil
+		}
+		return obj, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return object.TRUE, nil
+		}
+		return object.FALSE, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: v.Int()}, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > 1<<63-1 {
+			return nil, fmt.Errorf("%d overflows INTEGER", v.Uint())
+		}
+		return &object.Integer{Value: int64(v.Uint())}, nil
+
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: v.Float()}, nil
+
+	case reflect.String:
+		return &object.String{Value: v.String()}, nil
+
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return object.NULL, nil
+		}
+		return toObject(v.Elem())
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return object.NULL, nil
+		}
+		elements := make([]object.Object, v.Len())
+		for i := range elements {
+			element, err := toObject(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return &object.Array{Elements: elements}, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return object.NULL, nil
+		}
+		hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair, v.Len())}
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := setPair(hash, iter.Key(), iter.Value()); err != nil {
+				return nil, err
+			}
+		}
+		return hash, nil
+
+	case reflect.Struct:
+		hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+		for _, field := range structFields(v.Type()) {
+			if err := setPair(hash, reflect.ValueOf(field.key), v.Field(field.index)); err != nil {
+				return nil, err
+			}
+		}
+		return hash, nil
+
+	case reflect.Func:
+		if v.IsNil() {
+			return object.NULL, nil
+		}
+		return wrap(v)
+	}
+
+	return nil, fmt.Errorf("cannot convert %s to an object", v.Type())
+}
+
+func setPair(hash *object.Hash, key, value reflect.Value) error {
+	k, err := toObject(key)
+	if err != nil {
+		return err
+	}
+	hashable, ok := k.(object.Hashable)
+	if !ok {
+		return fmt.Errorf("unusable as hash key: %s", k.Type())
+	}
+
+	val, err := toObject(value)
+	if err != nil {
+		return err
+	}
+
+	hash.Pairs[hashable.HashKey()] = object.HashPair{Key: k, Value: val}
+	return nil
+}
+
+// structField is an exported field of a struct and its key in a hash
+type structField struct {
+	index int
+	key   string
+}
+
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" { // unexported
+			continue
+		}
+
+		key := field.Name
+		if tag := strings.Split(field.Tag.Get("monkey"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			key = tag
+		}
+
+		fields = append(fields, structField{index: i, key: key})
+	}
+	return fields
+}
+
+// FromObject convert obj to a Go value and store it in the value target points to.
+// It is the reverse of ToObject. Converted into an interface{}, an INTEGER is an int64, a FLOAT a float64,
+// an ARRAY a []interface{}, and a HASH a map[string]interface{}, or a map[interface{}]interface{}
+// when some of its keys are not strings.
+func FromObject(obj object.Object, target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("target of FromObject must be a non-nil pointer")
+	}
+	return fromObject(obj, v.Elem())
+}
+
+func fromObject(obj object.Object, v reflect.Value) error {
+	if v.Type().Implements(objectType) && reflect.TypeOf(obj).AssignableTo(v.Type()) {
+		v.Set(reflect.ValueOf(obj))
+		return nil
+	}
+
+	if obj == object.NULL {
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			break
+		}
+		value, err := toInterface(obj)
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(value))
+		}
+		return nil
+
+	case reflect.Bool:
+		if b, ok := obj.(*object.Boolean); ok {
+			v.SetBool(b.Value)
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := obj.(*object.Integer); ok {
+			if v.OverflowInt(i.Value) {
+				return fmt.Errorf("%d overflows %s", i.Value, v.Type())
+			}
+			v.SetInt(i.Value)
+			return nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i, ok := obj.(*object.Integer); ok {
+			if i.Value < 0 || v.OverflowUint(uint64(i.Value)) {
+				return fmt.Errorf("%d overflows %s", i.Value, v.Type())
+			}
+			v.SetUint(uint64(i.Value))
+			return nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		switch n := obj.(type) {
+		case *object.Float:
+			v.SetFloat(n.Value)
+			return nil
+		case *object.Integer:
+			v.SetFloat(float64(n.Value))
+			return nil
+		}
+
+	case reflect.String:
+		if s, ok := obj.(*object.String); ok {
+			v.SetString(s.Value)
+			return nil
+		}
+
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := fromObject(obj, elem.Elem()); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+
+	case reflect.Slice:
+		if arr, ok := obj.(*object.Array); ok {
+			slice := reflect.MakeSlice(v.Type(), len(arr.Elements), len(arr.Elements))
+			for i, element := range arr.Elements {
+				if err := fromObject(element, slice.Index(i)); err != nil {
+					return err
+				}
+			}
+			v.Set(slice)
+			return nil
+		}
+
+	case reflect.Array:
+		if arr, ok := obj.(*object.Array); ok {
+			if len(arr.Elements) != v.Len() {
+				return fmt.Errorf("cannot convert ARRAY of length %d to %s", len(arr.Elements), v.Type())
+			}
+			for i, element := range arr.Elements {
+				if err := fromObject(element, v.Index(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+	case reflect.Map:
+		if hash, ok := obj.(*object.Hash); ok {
+			m := reflect.MakeMapWithSize(v.Type(), len(hash.Pairs))
+			for _, pair := range hash.Pairs {
+				key := reflect.New(v.Type().Key()).Elem()
+				if err := fromObject(pair.Key, key); err != nil {
+					return err
+				}
+				value := reflect.New(v.Type().Elem()).Elem()
+				if err := fromObject(pair.Value, value); err != nil {
+					return err
+				}
+				m.SetMapIndex(key, value)
+			}
+			v.Set(m)
+			return nil
+		}
+
+	case reflect.Struct:
+		if hash, ok := obj.(*object.Hash); ok {
+			for _, field := range structFields(v.Type()) {
+				key := &object.String{Value: field.key}
+				pair, ok := hash.Pairs[key.HashKey()]
+				if !ok {
+					continue
+				}
+				if err := fromObject(pair.Value, v.Field(field.index)); err != nil {
+					return fmt.Errorf("field %s: %s", field.key, err)
+				}
+			}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("cannot convert %s to %s", obj.Type(), v.Type())
+}
+
+// toInterface return the natural Go value of obj
+func toInterface(obj object.Object) (interface{}, error) {
+	switch obj := obj.(type) {
+	case *object.Null:
+		return nil, nil
+	case *object.Boolean:
+		return obj.Value, nil
+	case *object.Integer:
+		return obj.Value, nil
+	case *object.Float:
+		return obj.Value, nil
+	case *object.String:
+		return obj.Value, nil
+
+	case *object.Array:
+		elements := make([]interface{}, len(obj.Elements))
+		for i, element := range obj.Elements {
+			value, err := toInterface(element)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = value
+		}
+		return elements, nil
+
+	case *object.Hash:
+		m := make(map[interface{}]interface{}, len(obj.Pairs))
+		stringKeys := make(map[string]interface{}, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			key, err := toInterface(pair.Key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := toInterface(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+			if s, ok := key.(string); ok {
+				stringKeys[s] = value
+			}
+		}
+		if len(stringKeys) == len(m) {
+			return stringKeys, nil
+		}
+		return m, nil
+	}
+
+	return obj, nil
+}
+
+// Wrap turn fn, which must be a Go function, into a builtin.
+// The arguments of a call are converted with FromObject to the parameter types of fn, and its result with ToObject.
+// fn may return nothing, a value, an error, or a value and an error. A non-nil error is returned as an ERROR.
+// When the first parameter of fn is an *object.IO, it receives the streams of the calling evaluation.
+func Wrap(fn interface{}) (*object.Builtin, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("cannot wrap %T: not a function", fn)
+	}
+	return wrap(v)
+}
+
+func wrap(fn reflect.Value) (*object.Builtin, error) {
+	t := fn.Type()
+
+	returnsError := t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType
+	numValues := t.NumOut()
+	if returnsError {
+		numValues--
+	}
+	if numValues > 1 {
+		return nil, fmt.Errorf("cannot wrap %s: too many results", t)
+	}
+
+	takesIO := t.NumIn() > 0 && t.In(0) == ioType
+
+	return &object.Builtin{Fn: func(stdio *object.IO, args ...object.Object) object.Object {
+		var in []reflect.Value
+		if takesIO {
+			in = append(in, reflect.ValueOf(stdio))
+		}
+
+		converted, err := convertArgs(t, len(in), args)
+		if err != nil {
+			return &object.Error{Message: err.Error()}
+		}
+		in = append(in, converted...)
+
+		out := fn.Call(in)
+
+		if returnsError {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return &object.Error{Message: err.Error()}
+			}
+		}
+		if numValues == 0 {
+			return object.NULL
+		}
+
+		result, err := toObject(out[0])
+		if err != nil {
+			return &object.Error{Message: err.Error()}
+		}
+		return result
+	}}, nil
+}
+
+// convertArgs convert the arguments of a call to the parameters of a function of type t, starting at the parameter first
+func convertArgs(t reflect.Type, first int, args []object.Object) ([]reflect.Value, error) {
+	numParams := t.NumIn() - first
+	if t.IsVariadic() {
+		if len(args) < numParams-1 {
+			return nil, fmt.Errorf("wrong number of arguments. got=%d, want at least %d", len(args), numParams-1)
+		}
+	} else if len(args) != numParams {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=%d", len(args), numParams)
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var paramType reflect.Type
+		if t.IsVariadic() && i >= numParams-1 {
+			paramType = t.In(first + numParams - 1).Elem()
+		} else {
+			paramType = t.In(first + i)
+		}
+
+		param := reflect.New(paramType).Elem()
+		if err := fromObject(arg, param); err != nil {
+			return nil, fmt.Errorf("argument %d: %s", i+1, err)
+		}
+		in[i] = param
+	}
+	return in, nil
+}
@@ -0,0 +1,169 @@
+package interpreter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/NAKKA-K/learn-interpreter-in-go/object"
+)
+
+type point struct {
+	X      int
+	Y      int    `monkey:"y"`
+	Label  string `monkey:"-"`
+	hidden int
+}
+
+func TestToObject(t *testing.T) {
+	var nilPointer *point
+
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{nil, "null"},
+		{nilPointer, "null"},
+		{true, "true"},
+		{42, "42"},
+		{uint8(7), "7"},
+		{1.5, "1.5"},
+		{"monkey", "monkey"},
+		{[]int{1, 2}, "[1, 2]"},
+		{[2]string{"a", "b"}, "[a, b]"},
+		{map[string]int{"a": 1}, "{a: 1}"},
+		{struct{ Name string }{"monkey"}, "{Name: monkey}"},
+		{&struct {
+			N int    `monkey:"n"`
+			S string `monkey:"-"`
+		}{N: 3}, "{n: 3}"},
+		{&object.Integer{Value: 5}, "5"},
+	}
+
+	for _, tt := range tests {
+		obj, err := ToObject(tt.input)
+		if err != nil {
+			t.Errorf("unexpected error for %#v: %s", tt.input, err)
+			continue
+		}
+		if obj.Inspect() != tt.expected {
+			t.Errorf("wrong object for %#v. expected=%s, got=%s", tt.input, tt.expected, obj.Inspect())
+		}
+	}
+
+	if _, err := ToObject(make(chan int)); err == nil {
+		t.Errorf("a channel is converted")
+	}
+	if _, err := ToObject(map[[1]int]int{{1}: 1}); err == nil {
+		t.Errorf("a map with array keys is converted")
+	}
+}
+
+func TestFromObject(t *testing.T) {
+	interp := New()
+	obj, err := interp.Run(`{"X": 1, "y": 2, "Label": "ignored"}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var p point
+	if err := FromObject(obj, &p); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if p != (point{X: 1, Y: 2}) {
+		t.Errorf("wrong struct. got=%+v", p)
+	}
+
+	var value interface{}
+	obj, err = interp.Run(`[1, 2.5, "a", true, {"k": if (false) { 1 }}]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := FromObject(obj, &value); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []interface{}{int64(1), 2.5, "a", true, map[string]interface{}{"k": nil}}
+	if !reflect.DeepEqual(value, expected) {
+		t.Errorf("wrong value. expected=%#v, got=%#v", expected, value)
+	}
+
+	var small int8
+	if err := FromObject(&object.Integer{Value: 300}, &small); err == nil {
+		t.Errorf("overflow is not an error")
+	}
+	var s string
+	if err := FromObject(&object.Integer{Value: 1}, &s); err == nil || err.Error() != "cannot convert INTEGER to string" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+	if err := FromObject(object.NULL, s); err == nil {
+		t.Errorf("a non-pointer target is accepted")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	var out bytes.Buffer
+
+	interp := New()
+	interp.Stdout = &out
+
+	funcs := map[string]interface{}{
+		"add": func(a, b int) int { return a + b },
+		"join": func(sep string, parts ...string) string {
+			return strings.Join(parts, sep)
+		},
+		"divide": func(a, b float64) (float64, error) {
+			if b == 0 {
+				return 0, errors.New("division by zero")
+			}
+			return a / b, nil
+		},
+		"nothing": func() {},
+		"greet": func(stdio *object.IO, name string) {
+			fmt.Fprintf(stdio.Stdout, "hello %s", name)
+		},
+	}
+	for name, fn := range funcs {
+		if err := interp.RegisterFunc(name, fn); err != nil {
+			t.Fatalf("cannot register %s: %s", name, err)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"add(1, 2)", "3"},
+		{`join("-", "a", "b", "c")`, "a-b-c"},
+		{`join("-")`, ""},
+		{"divide(3, 2)", "1.5"},
+		{"nothing()", "null"},
+		{"add(1)", "ERROR: 1:1: wrong number of arguments. got=1, want=2"},
+		{`add(1, "2")`, "ERROR: 1:1: argument 2: cannot convert STRING to int"},
+		{"join()", "ERROR: 1:1: wrong number of arguments. got=0, want at least 1"},
+		{"divide(1, 0)", "ERROR: 1:1: division by zero"},
+	}
+
+	for _, tt := range tests {
+		result, err := interp.Run(tt.input)
+		if err != nil {
+			result = err.(*object.Error)
+		}
+		if result.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, result.Inspect())
+		}
+	}
+
+	if _, err := interp.Run(`greet("monkey")`); err != nil || out.String() != "hello monkey" {
+		t.Errorf("wrong output of greet. got=%q, %v", out.String(), err)
+	}
+
+	if _, err := Wrap(42); err == nil {
+		t.Errorf("a non-function is wrapped")
+	}
+	if _, err := Wrap(func() (int, int) { return 1, 2 }); err == nil {
+		t.Errorf("a function with two values is wrapped")
+	}
+}
@@ -0,0 +1,145 @@
+// Package interpreter embeds the language in Go programs
+package interpreter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/NAKKA-K/learn-interpreter-in-go/evaluator"
+	"github.com/NAKKA-K/learn-interpreter-in-go/lexer"
+	"github.com/NAKKA-K/learn-interpreter-in-go/object"
+	"github.com/NAKKA-K/learn-interpreter-in-go/parser"
+)
+
+// Interpreter run sources one after another, keeping their globals and macros between runs
+type Interpreter struct {
+	// Stdin, Stdout and Stderr are the streams of the programs, those of the process by default
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// MaxSteps is the number of loop iterations and function calls a run may take, 0 means no limit
+	MaxSteps int
+
+	// MaxDepth is the number of nested function calls a run may make, evaluator.MaxDepth when 0
+	MaxDepth int
+
+	// ModulePath is the directories searched by import, after the current directory
+	ModulePath []string
+
+	stdio *object.IO // kept between runs, so that the input it buffered is not lost
+
+	builtins *object.Environment // the builtins registered on this interpreter
+	globals  *object.Environment // enclosed by builtins, so that globals can shadow them
+	macros   *object.Environment
+	modules  *evaluator.Modules // the imported files, evaluated once per interpreter
+}
+
+// New return an Interpreter without globals
+func New() *Interpreter {
+	i := &Interpreter{
+		Stdin:    os.Stdin,
+		Stdout:   os.Stdout,
+		Stderr:   os.Stderr,
+		builtins: object.NewEnvironment(),
+		macros:   object.NewEnvironment(),
+	}
+	i.globals = object.NewEnclosedEnvironment(i.builtins)
+	i.modules = evaluator.NewModules()
+	i.modules.Builtins = i.builtins
+	return i
+}
+
+// RegisterBuiltin make builtin callable as name by the programs of this interpreter only
+func (i *Interpreter) RegisterBuiltin(name string, builtin *object.Builtin) {
+	i.builtins.Set(name, builtin)
+}
+
+// RegisterFunc make the Go function fn callable as name, see Wrap
+func (i *Interpreter) RegisterFunc(name string, fn interface{}) error {
+	builtin, err := Wrap(fn)
+	if err != nil {
+		return err
+	}
+	i.RegisterBuiltin(name, builtin)
+	return nil
+}
+
+// Set define the global name, as if a program had run `let name = value;`
+func (i *Interpreter) Set(name string, value object.Object) {
+	i.globals.Set(name, value)
+}
+
+// Get return the global name, or a builtin registered as name
+func (i *Interpreter) Get(name string) (object.Object, bool) {
+	return i.globals.Get(name)
+}
+
+// Run evaluate source and return the value of its last statement, or NULL when it has none.
+// A source which cannot be parsed is a *SyntaxError, a runtime error is an *object.Error.
+func (i *Interpreter) Run(source string) (object.Object, error) {
+	return i.RunContext(context.Background(), source)
+}
+
+// RunContext evaluate source like Run, but stop with an error when ctx is done.
+// A panic while evaluating, such as in a registered builtin, is returned as an error too.
+func (i *Interpreter) RunContext(ctx context.Context, source string) (result object.Object, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("internal error: %v", r)
+		}
+	}()
+
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &SyntaxError{Source: source, Errors: p.ParseErrors()}
+	}
+
+	evaluator.DefineMacros(program, i.macros)
+	expanded := evaluator.ExpandMacros(program, i.macros)
+
+	i.modules.Path = i.ModulePath
+	opts := evaluator.Options{
+		MaxSteps: i.MaxSteps,
+		MaxDepth: i.MaxDepth,
+		IO:       i.io(),
+		Modules:  i.modules,
+	}
+	evaluated := evaluator.EvalContext(ctx, expanded, i.globals, opts)
+	if errObj, ok := evaluated.(*object.Error); ok {
+		return nil, errObj
+	}
+	if evaluated == nil {
+		return object.NULL, nil
+	}
+	return evaluated, nil
+}
+
+// io return the streams of the programs, with the buffer of Stdin kept as long as Stdin is not replaced
+func (i *Interpreter) io() *object.IO {
+	if i.stdio == nil || i.stdio.Stdin != i.Stdin {
+		i.stdio = &object.IO{Stdin: i.Stdin}
+	}
+	i.stdio.Stdout = i.Stdout
+	i.stdio.Stderr = i.Stderr
+	return i.stdio
+}
+
+// SyntaxError is the error of a source which cannot be parsed
+type SyntaxError struct {
+	Source string
+	Errors []*parser.ParseError
+}
+
+// Error return the parse errors rendered with the offending source lines
+func (e *SyntaxError) Error() string {
+	var out strings.Builder
+	for _, err := range e.Errors {
+		out.WriteString(err.Render(e.Source))
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
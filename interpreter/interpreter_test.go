@@ -0,0 +1,131 @@
+package interpreter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/NAKKA-K/learn-interpreter-in-go/object"
+)
+
+func TestRun(t *testing.T) {
+	interp := New()
+
+	if _, err := interp.Run("let double = fn(x) { x * 2 };"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	result, err := interp.Run("double(21)")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result.Inspect() != "42" {
+		t.Errorf("wrong result. expected=42, got=%s", result.Inspect())
+	}
+
+	result, err = interp.Run("let x = 1;")
+	if err != nil || result != object.NULL {
+		t.Errorf("a statement without value does not return NULL. got=%v, %v", result, err)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	interp := New()
+
+	_, err := interp.Run("let = 1;")
+	syntaxErr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("no syntax error returned. got=%T(%v)", err, err)
+	}
+	if len(syntaxErr.Errors) == 0 || !strings.Contains(syntaxErr.Error(), "let = 1;") {
+		t.Errorf("syntax error does not render the source. got=%q", syntaxErr.Error())
+	}
+
+	_, err = interp.Run("1 + true")
+	errObj, ok := err.(*object.Error)
+	if !ok {
+		t.Fatalf("no runtime error returned. got=%T(%v)", err, err)
+	}
+	if errObj.Message != "type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+
+	interp.MaxSteps = 10
+	_, err = interp.Run("while (true) { }")
+	if errObj, ok := err.(*object.Error); !ok || errObj.Kind != object.BudgetExhaustedError {
+		t.Errorf("step budget is not enforced. got=%v", err)
+	}
+}
+
+func TestRunRecoversPanics(t *testing.T) {
+	interp := New()
+	interp.RegisterBuiltin("crash", &object.Builtin{Fn: func(stdio *object.IO, args ...object.Object) object.Object {
+		panic("boom")
+	}})
+
+	_, err := interp.Run("crash()")
+	if err == nil || err.Error() != "internal error: boom" {
+		t.Errorf("panic is not returned as an error. got=%v", err)
+	}
+
+	_, err = interp.Run("let f = fn(a, b) { b }; f(1)")
+	if errObj, ok := err.(*object.Error); !ok || errObj.Message != "wrong number of arguments: want=2, got=1" {
+		t.Errorf("wrong error for missing arguments. got=%v", err)
+	}
+
+	if result, err := interp.Run("1 + 1"); err != nil || result.Inspect() != "2" {
+		t.Errorf("interpreter is not usable after a panic. got=%v, %v", result, err)
+	}
+}
+
+func TestGlobals(t *testing.T) {
+	interp := New()
+	interp.Set("name", &object.String{Value: "monkey"})
+
+	if _, err := interp.Run(`let greeting = "hello " + name;`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	greeting, ok := interp.Get("greeting")
+	if !ok || greeting.Inspect() != "hello monkey" {
+		t.Errorf("wrong global. got=%v", greeting)
+	}
+	if _, ok := interp.Get("undefined"); ok {
+		t.Errorf("undefined global is found")
+	}
+}
+
+func TestStdinAcrossRuns(t *testing.T) {
+	interp := New()
+	interp.Stdin = strings.NewReader("first\nsecond\n")
+
+	for _, expected := range []string{"first", "second"} {
+		line, err := interp.Run("read_line()")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if line.Inspect() != expected {
+			t.Errorf("wrong line. expected=%q, got=%q", expected, line.Inspect())
+		}
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	var out bytes.Buffer
+
+	interp := New()
+	interp.Stdout = &out
+	interp.RegisterBuiltin("answer", &object.Builtin{Fn: func(stdio *object.IO, args ...object.Object) object.Object {
+		return &object.Integer{Value: 42}
+	}})
+
+	if _, err := interp.Run(`puts(answer(), "done")`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if out.String() != "42\ndone\n" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+
+	if _, err := New().Run("answer()"); err == nil {
+		t.Errorf("a builtin of another interpreter is callable")
+	}
+}
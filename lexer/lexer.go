@@ -1,46 +1,128 @@
 package lexer
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
 	"github.com/NAKKA-K/learn-interpreter-in-go/token"
 )
 
+// Error is a problem found while reading the input
+type Error struct {
+	Pos token.Position
+	Msg string
+
+	// Unterminated is set when the input ended inside a string or a block comment, which more input may complete
+	Unterminated bool
+}
+
+func (e Error) Error() string { return e.Pos.String() + ": " + e.Msg }
+
 // Lexer is program reader
 type Lexer struct {
+	filename     string
 	input        string
-	position     int  // Last read position
-	readPosition int  // Next read position
-	ch           byte // Character pointing to
+	position     int  // Last read position (byte offset)
+	readPosition int  // Next read position (byte offset)
+	ch           rune // Character pointing to
+	line         int  // Line of ch
+	column       int  // Column of ch, counted in runes
+
+	errors []Error
 }
 
 // New lexer generator. It't constructor
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewFile("", input)
+}
+
+// NewFile generate lexer whose token positions refer to filename
+func NewFile(filename, input string) *Lexer {
+	l := &Lexer{filename: filename, input: input, line: 1}
 	l.readChar()
+	l.skipShebang()
 	return l
 }
 
+// skipShebang ignore a "#!" interpreter line at the beginning of a script
+func (l *Lexer) skipShebang() {
+	if l.ch != '#' || l.peekChar() != '!' {
+		return
+	}
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+}
+
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+
+	size := 1
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, size = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 	l.position = l.readPosition
-	l.readPosition++
+	l.readPosition += size
 }
 
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
 	}
-	return l.input[l.readPosition]
+	ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return ch
+}
+
+// Errors return the problems found in the input read so far
+func (l *Lexer) Errors() []Error {
+	return l.errors
+}
+
+func (l *Lexer) error(pos token.Position, msg string) {
+	l.errors = append(l.errors, Error{Pos: pos, Msg: msg})
+}
+
+// unterminated report a string or a block comment starting at pos, which the input ended inside of
+func (l *Lexer) unterminated(pos token.Position, msg string) {
+	l.errors = append(l.errors, Error{Pos: pos, Msg: msg, Unterminated: true})
+}
+
+func (l *Lexer) pos() token.Position {
+	return token.Position{
+		Filename: l.filename,
+		Offset:   l.position,
+		Line:     l.line,
+		Column:   l.column,
+	}
 }
 
 // NextToken to determined
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
+	comments := l.skipTrivia()
 
-	l.skipWhitespace()
+	start := l.pos()
+	tok := l.readToken()
+	tok.Comments = comments
+	tok.Pos = start
+	tok.End = l.pos()
+	if tok.Type == token.EOF {
+		tok.End = start
+	}
+
+	return tok
+}
+
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
 
 	switch l.ch {
 	case '=':
@@ -53,9 +135,9 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		tok = l.newCompoundToken(token.PLUS, token.PLUS_ASSIGN)
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		tok = l.newCompoundToken(token.MINUS, token.MINUS_ASSIGN)
 	case '!':
 		if l.peekChar() == '=' { // judge !=
 			ch := l.ch
@@ -66,13 +148,29 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		tok = l.newCompoundToken(token.SLASH, token.SLASH_ASSIGN)
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		tok = l.newCompoundToken(token.ASTERISK, token.ASTERISK_ASSIGN)
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		tok = l.newCompoundToken(token.LT, token.LT_EQ)
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		tok = l.newCompoundToken(token.GT, token.GT_EQ)
+	case '&':
+		if l.peekChar() == '&' {
+			l.readChar()
+			tok = token.Token{Type: token.AND, Literal: "&&"}
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			l.readChar()
+			tok = token.Token{Type: token.OR, Literal: "||"}
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case '(':
@@ -94,6 +192,8 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.RBRACKET, l.ch)
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+	case '.':
+		tok = newToken(token.DOT, l.ch)
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -103,8 +203,7 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookupIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
+			tok.Literal, tok.Type = l.readNumber()
 			return tok
 		}
 		tok = newToken(token.ILLEGAL, l.ch)
@@ -114,10 +213,21 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
+// newCompoundToken return compound ("+=", "<=") if the next character is '=', otherwise single ("+", "<")
+func (l *Lexer) newCompoundToken(single, compound token.TokenType) token.Token {
+	if l.peekChar() != '=' {
+		return newToken(single, l.ch)
+	}
+
+	ch := l.ch
+	l.readChar()
+	return token.Token{Type: compound, Literal: string(ch) + string(l.ch)}
+}
+
 func (l *Lexer) readIdentifier() string {
 	position := l.position
 	for isLetter(l.ch) {
@@ -126,35 +236,199 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || ch == '!' || ch == '?'
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_' || ch == '!' || ch == '?'
 }
 
-func (l *Lexer) readNumber() string {
+// readNumber read an integer ("42") or a float ("3.14", "1e-9", "2.5E+3")
+func (l *Lexer) readNumber() (string, token.TokenType) {
 	position := l.position
+	tokenType := token.TokenType(token.INT)
+
+	l.readDigits()
+
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+
+	if (l.ch == 'e' || l.ch == 'E') && l.isExponentNext() {
+		tokenType = token.FLOAT
+		l.readChar()
+		if l.ch == '+' || l.ch == '-' {
+			l.readChar()
+		}
+		l.readDigits()
+	}
+
+	return l.input[position:l.position], tokenType
+}
+
+func (l *Lexer) readDigits() {
 	for isDigit(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
 }
 
+// isExponentNext report whether the 'e' at the current position starts an exponent
+func (l *Lexer) isExponentNext() bool {
+	next := l.readPosition
+	if next < len(l.input) && (l.input[next] == '+' || l.input[next] == '-') {
+		next++
+	}
+	return next < len(l.input) && isDigit(rune(l.input[next]))
+}
+
+// readString read a double-quoted string and return its value with the escape sequences decoded
 func (l *Lexer) readString() string {
-	position := l.position + 1
+	start := l.pos()
+	var out strings.Builder
+
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
-			break
+
+		switch l.ch {
+		case '"':
+			return out.String()
+		case 0:
+			l.unterminated(start, "unterminated string")
+			return out.String()
+		case '\\':
+			l.readEscape(&out)
+		default:
+			out.WriteRune(l.ch)
 		}
 	}
-	return l.input[position:l.position]
 }
 
-func isDigit(ch byte) bool {
+// readEscape decode the escape sequence starting at the current backslash
+func (l *Lexer) readEscape(out *strings.Builder) {
+	start := l.pos()
+	l.readChar()
+
+	switch l.ch {
+	case 'n':
+		out.WriteByte('\n')
+	case 't':
+		out.WriteByte('\t')
+	case 'r':
+		out.WriteByte('\r')
+	case '0':
+		out.WriteByte(0)
+	case '\\', '"', '\'':
+		out.WriteRune(l.ch)
+	case 'u':
+		l.readUnicodeEscape(start, out)
+	case 0:
+		// reported as an unterminated string by the caller
+	default:
+		l.error(start, fmt.Sprintf("unknown escape sequence \\%c", l.ch))
+		out.WriteRune(l.ch)
+	}
+}
+
+// readUnicodeEscape decode "\uXXXX" or "\u{X...}" after the 'u' has been read
+func (l *Lexer) readUnicodeEscape(start token.Position, out *strings.Builder) {
+	var digits strings.Builder
+
+	if l.peekChar() == '{' {
+		l.readChar()
+		for isHexDigit(l.peekChar()) {
+			l.readChar()
+			digits.WriteRune(l.ch)
+		}
+		if l.peekChar() != '}' {
+			l.error(start, "unterminated unicode escape sequence")
+			return
+		}
+		l.readChar()
+	} else {
+		for i := 0; i < 4 && isHexDigit(l.peekChar()); i++ {
+			l.readChar()
+			digits.WriteRune(l.ch)
+		}
+		if digits.Len() != 4 {
+			l.error(start, "unicode escape sequence needs 4 hex digits")
+			return
+		}
+	}
+
+	code, err := strconv.ParseUint(digits.String(), 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		l.error(start, fmt.Sprintf("invalid unicode escape sequence \\u{%s}", digits.String()))
+		return
+	}
+	out.WriteRune(rune(code))
+}
+
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
 func (l *Lexer) skipWhitespace() {
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
 		l.readChar()
 	}
 }
+
+// skipTrivia skip whitespace and comments, and return the comments
+func (l *Lexer) skipTrivia() []token.Comment {
+	var comments []token.Comment
+
+	for {
+		l.skipWhitespace()
+
+		if l.ch != '/' || (l.peekChar() != '/' && l.peekChar() != '*') {
+			return comments
+		}
+
+		start := l.pos()
+		if l.peekChar() == '/' {
+			l.skipLineComment()
+		} else {
+			l.skipBlockComment()
+		}
+
+		comments = append(comments, token.Comment{
+			Text: l.input[start.Offset:l.position],
+			Pos:  start,
+			End:  l.pos(),
+		})
+	}
+}
+
+func (l *Lexer) skipLineComment() {
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+}
+
+// skipBlockComment skip "/* ... */" which may contain nested block comments
+func (l *Lexer) skipBlockComment() {
+	start := l.pos()
+	depth := 0
+
+	for {
+		switch {
+		case l.ch == 0:
+			l.unterminated(start, "unterminated block comment")
+			return
+		case l.ch == '/' && l.peekChar() == '*':
+			depth++
+			l.readChar()
+		case l.ch == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+			if depth == 0 {
+				l.readChar()
+				return
+			}
+		}
+		l.readChar()
+	}
+}
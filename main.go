@@ -1,19 +1,219 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/user"
 
+	"github.com/NAKKA-K/learn-interpreter-in-go/ast"
+	"github.com/NAKKA-K/learn-interpreter-in-go/compiler"
+	"github.com/NAKKA-K/learn-interpreter-in-go/evaluator"
+	"github.com/NAKKA-K/learn-interpreter-in-go/lexer"
+	"github.com/NAKKA-K/learn-interpreter-in-go/object"
+	"github.com/NAKKA-K/learn-interpreter-in-go/parser"
 	"github.com/NAKKA-K/learn-interpreter-in-go/repl"
+	"github.com/NAKKA-K/learn-interpreter-in-go/vm"
 )
 
+const usage = `Usage:
+  monkey                      start the interactive REPL
+  monkey script.mk [args...]  run a script file
+  monkey - [args...]          run a script read from stdin
+  monkey -e 'expr' [args...]  evaluate expr and print the result
+
+When stdin is not a terminal and no script is given, the script is read from stdin.
+The remaining arguments are available to the script as the array 'args'.
+import looks for modules next to the importing file, then in the directories of MONKEYPATH.
+A module exposes only the bindings declared with 'export let'.
+Programs are run by the tree-walking evaluator unless -engine=vm is given.
+The vm engine does not support import yet.
+`
+
 func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	expr := flag.String("e", "", "evaluate the given source and print the result")
+	engineName := flag.String("engine", "eval", "how programs are run: 'eval' or 'vm', which cannot import modules")
+	maxDepth := flag.Int("max-depth", evaluator.MaxDepth, "maximum number of nested function calls")
+	flag.Parse()
+
+	engine, ok := engines[*engineName]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown engine %q: must be 'eval' or 'vm'\n", *engineName)
+		os.Exit(2)
+	}
+
+	args := flag.Args()
+
+	switch {
+	case *expr != "":
+		os.Exit(run("<cmdline>", *expr, args, engine, *maxDepth, os.Stdout, true))
+
+	case len(args) > 0:
+		filename, src, err := readScript(args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		os.Exit(run(filename, src, args[1:], engine, *maxDepth, os.Stdout, false))
+
+	case !isTerminal(os.Stdin):
+		_, src, err := readScript("-")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		os.Exit(run("<stdin>", src, args, engine, *maxDepth, os.Stdout, false))
+
+	default:
+		startREPL(engine, *maxDepth)
+	}
+}
+
+var engines = map[string]repl.Engine{
+	"eval": repl.EngineEval,
+	"vm":   repl.EngineVM,
+}
+
+func startREPL(engine repl.Engine, maxDepth int) {
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
 	}
 	fmt.Printf("Hello %s! This is the interpreter-in-go programming ranguage!\n", user.Username)
-	fmt.Println("Feel free to type in commands")
-	repl.Start(os.Stdin, os.Stdout)
+	fmt.Println("Feel free to type in commands, or :help for the commands of the REPL")
+	repl.Start(os.Stdin, os.Stdout, engine, maxDepth)
+}
+
+// readScript read a script from path, or from stdin when path is "-"
+func readScript(path string) (string, string, error) {
+	if path == "-" {
+		src, err := ioutil.ReadAll(os.Stdin)
+		return "<stdin>", string(src), err
+	}
+
+	src, err := ioutil.ReadFile(path)
+	return path, string(src), err
+}
+
+// run evaluate src and return the process exit status
+func run(filename, src string, args []string, engine repl.Engine, maxDepth int, out io.Writer, printResult bool) int {
+	l := lexer.NewFile(filename, src)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		for _, err := range p.ParseErrors() {
+			fmt.Fprint(os.Stderr, err.Render(src))
+		}
+		return 1
+	}
+
+	macroEnv := object.NewEnvironment()
+	evaluator.DefineMacros(program, macroEnv)
+	expanded := evaluator.ExpandMacros(program, macroEnv).(*ast.Program)
+
+	if engine == repl.EngineVM {
+		if imp := findImport(expanded); imp != nil {
+			fmt.Fprintf(os.Stderr, "%s: import is not supported by the vm engine, run the program with -engine=eval\n", imp.Pos())
+			return 2
+		}
+	}
+
+	stdio := &object.IO{Stdin: os.Stdin, Stdout: out, Stderr: os.Stderr}
+
+	var evaluated object.Object
+	var err error
+	if engine == repl.EngineVM {
+		evaluated, err = runVM(expanded, newArgsArray(args), stdio, maxDepth)
+	} else {
+		evaluated, err = runEvaluator(expanded, newArgsArray(args), stdio, maxDepth)
+	}
+
+	if err != nil {
+		if errObj, ok := err.(*object.Error); ok {
+			fmt.Fprintln(os.Stderr, errObj.Traceback())
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return 1
+	}
+
+	if printResult && evaluated != nil && evaluated != object.NULL {
+		io.WriteString(out, evaluated.Inspect())
+		io.WriteString(out, "\n")
+	}
+
+	return 0
+}
+
+func runEvaluator(program *ast.Program, args *object.Array, stdio *object.IO, maxDepth int) (object.Object, error) {
+	env := object.NewEnvironment()
+	env.Set("args", args)
+
+	evaluated := evaluator.EvalContext(context.Background(), program, env, evaluator.Options{IO: stdio, MaxDepth: maxDepth})
+	if errObj, ok := evaluated.(*object.Error); ok {
+		return nil, errObj
+	}
+	return evaluated, nil
+}
+
+func runVM(program *ast.Program, args *object.Array, stdio *object.IO, maxDepth int) (object.Object, error) {
+	symbolTable := compiler.NewSymbolTable()
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+	argsSymbol := symbolTable.Define("args")
+
+	comp := compiler.NewWithState(symbolTable, []object.Object{})
+	if err := comp.Compile(program); err != nil {
+		return nil, err
+	}
+
+	globals := make([]object.Object, vm.GlobalsSize)
+	globals[argsSymbol.Index] = args
+
+	machine := vm.NewWithGlobalsStore(comp.Bytecode(), globals)
+	machine.SetIO(stdio)
+	if maxDepth > 0 {
+		machine.SetMaxDepth(maxDepth)
+	}
+	if err := machine.Run(); err != nil {
+		return nil, err
+	}
+	return machine.Result(), nil
+}
+
+// findImport return an import statement of program, or nil when it has none
+func findImport(program *ast.Program) *ast.ImportStatement {
+	var found *ast.ImportStatement
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		if imp, ok := node.(*ast.ImportStatement); ok && found == nil {
+			found = imp
+		}
+		return node
+	})
+	return found
+}
+
+func newArgsArray(args []string) *object.Array {
+	elements := make([]object.Object, len(args))
+	for i, arg := range args {
+		elements[i] = &object.String{Value: arg}
+	}
+	return &object.Array{Elements: elements}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
 }
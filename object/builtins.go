@@ -0,0 +1,224 @@
+package object
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode/utf8"
+)
+
+// Builtins is the list of builtin functions.
+// The order is part of the bytecode format: the compiler refers to a builtin by its index.
+var Builtins = []struct {
+	Name    string
+	Builtin *Builtin
+}{
+	{
+		"len",
+		&Builtin{Fn: func(stdio *IO, args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *Array:
+				return &Integer{Value: int64(len(arg.Elements))}
+			case *String:
+				return &Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+			default:
+				return newError("argument to `len` not supported, got %s", args[0].Type())
+			}
+		}},
+	},
+	{
+		"first",
+		&Builtin{Fn: func(stdio *IO, args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if args[0].Type() != ARRAY_OBJ {
+				return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
+			}
+
+			arr := args[0].(*Array)
+			if len(arr.Elements) > 0 {
+				return arr.Elements[0]
+			}
+
+			return NULL
+		}},
+	},
+	{
+		"last",
+		&Builtin{Fn: func(stdio *IO, args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if args[0].Type() != ARRAY_OBJ {
+				return newError("argument to `last` must be ARRAY, got %s", args[0].Type())
+			}
+
+			arr := args[0].(*Array)
+			length := len(arr.Elements)
+			if length > 0 {
+				return arr.Elements[length-1]
+			}
+
+			return NULL
+		}},
+	},
+	{
+		"rest",
+		&Builtin{Fn: func(stdio *IO, args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if args[0].Type() != ARRAY_OBJ {
+				return newError("argument to `rest` must be ARRAY, got %s", args[0].Type())
+			}
+
+			arr := args[0].(*Array)
+			length := len(arr.Elements)
+			if length > 0 {
+				newElements := make([]Object, length-1, length-1)
+				copy(newElements, arr.Elements[1:length])
+				return &Array{Elements: newElements}
+			}
+
+			return NULL
+		}},
+	},
+	{
+		"push",
+		&Builtin{Fn: func(stdio *IO, args ...Object) Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			if args[0].Type() != ARRAY_OBJ {
+				return newError("argument to `push` must be ARRAY, got %s", args[0].Type())
+			}
+
+			arr := args[0].(*Array)
+			length := len(arr.Elements)
+
+			newElements := make([]Object, length+1, length+1)
+			copy(newElements, arr.Elements)
+			newElements[length] = args[1]
+			return &Array{Elements: newElements}
+		}},
+	},
+	{
+		"puts",
+		&Builtin{Fn: func(stdio *IO, args ...Object) Object {
+			for _, arg := range args {
+				fmt.Fprintln(stdio.Stdout, arg.Inspect())
+			}
+
+			return NULL
+		}},
+	},
+	{
+		"print",
+		&Builtin{Fn: func(stdio *IO, args ...Object) Object {
+			printObjects(stdio.Stdout, args)
+			return NULL
+		}},
+	},
+	{
+		"eprint",
+		&Builtin{Fn: func(stdio *IO, args ...Object) Object {
+			printObjects(stdio.Stderr, args)
+			return NULL
+		}},
+	},
+	{
+		"input",
+		&Builtin{Fn: func(stdio *IO, args ...Object) Object {
+			if len(args) > 1 {
+				return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+			}
+			if len(args) == 1 {
+				fmt.Fprint(stdio.Stdout, args[0].Inspect())
+			}
+
+			return readLine(stdio)
+		}},
+	},
+	{
+		"read_line",
+		&Builtin{Fn: func(stdio *IO, args ...Object) Object {
+			if len(args) != 0 {
+				return newError("wrong number of arguments. got=%d, want=0", len(args))
+			}
+
+			return readLine(stdio)
+		}},
+	},
+	{
+		"read_all",
+		&Builtin{Fn: func(stdio *IO, args ...Object) Object {
+			if len(args) != 0 {
+				return newError("wrong number of arguments. got=%d, want=0", len(args))
+			}
+
+			all, err := ioutil.ReadAll(stdio.Reader())
+			if err != nil {
+				return newError("cannot read stdin: %s", err)
+			}
+			return &String{Value: string(all)}
+		}},
+	},
+	{
+		"lines",
+		&Builtin{Fn: func(stdio *IO, args ...Object) Object {
+			if len(args) != 0 {
+				return newError("wrong number of arguments. got=%d, want=0", len(args))
+			}
+
+			return &Iterator{Next: func() (Object, bool) {
+				line := readLine(stdio)
+				return line, line != NULL
+			}}
+		}},
+	},
+}
+
+// printObjects write args to w as print does: separated by a space, without a newline
+func printObjects(w io.Writer, args []Object) {
+	for i, arg := range args {
+		if i > 0 {
+			fmt.Fprint(w, " ")
+		}
+		fmt.Fprint(w, arg.Inspect())
+	}
+}
+
+// readLine return the next line of stdin without its line ending, or NULL at the end of the input
+func readLine(stdio *IO) Object {
+	line, err := stdio.Reader().ReadString('\n')
+	if err != nil && err != io.EOF {
+		return newError("cannot read stdin: %s", err)
+	}
+	if err == io.EOF && line == "" {
+		return NULL
+	}
+
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	return &String{Value: line}
+}
+
+// GetBuiltinByName return the builtin function called name
+func GetBuiltinByName(name string) *Builtin {
+	for _, def := range Builtins {
+		if def.Name == name {
+			return def.Builtin
+		}
+	}
+	return nil
+}
+
+func newError(format string, a ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, a...)}
+}
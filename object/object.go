@@ -1,12 +1,20 @@
 package object
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/NAKKA-K/learn-interpreter-in-go/ast"
+	"github.com/NAKKA-K/learn-interpreter-in-go/code"
+	"github.com/NAKKA-K/learn-interpreter-in-go/token"
 )
 
 // ObjectType is type string identifier
@@ -20,17 +28,32 @@ type Object interface {
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE_OBJ"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
 	STRING_OBJ       = "STRING"
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	HASH_OBJ         = "HASH"
+	ITERATOR_OBJ     = "ITERATOR"
 	QUOTE_OBJ        = "QUOTE"
 	MACRO_OBJ        = "MACRO"
+	MODULE_OBJ       = "MODULE"
+
+	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
+	UPVALUE_OBJ           = "UPVALUE"
+)
+
+// NULL, TRUE and FALSE are shared by every evaluation, so that they can be compared by identity
+var (
+	NULL  = &Null{}
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
 )
 
 // Integer is from IntegerLiteral
@@ -42,6 +65,21 @@ type Integer struct {
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 
+// Float is from FloatLiteral
+type Float struct {
+	Value float64
+}
+
+// Inspect return "1.5", "2.0" or "1e-09"
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") { // keep "2.0" distinguishable from the integer 2
+		s += ".0"
+	}
+	return s
+}
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+
 // Boolean is from ast.Boolean
 type Boolean struct {
 	Value bool
@@ -67,17 +105,89 @@ type ReturnValue struct {
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 
+// Break is signal of "break" which unwinds to the innermost loop
+type Break struct{}
+
+// Inspect return "break"
+func (b *Break) Inspect() string  { return "break" }
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+
+// Continue is signal of "continue" which unwinds to the innermost loop
+type Continue struct{}
+
+// Inspect return "continue"
+func (c *Continue) Inspect() string  { return "continue" }
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+
 // Error include error message
 type Error struct {
+	Kind    ErrorKind
 	Message string
+	Pos     token.Position // where the error occurred, if known
+	Stack   []Frame        // the function calls the error propagated through, innermost first
+}
+
+// ErrorKind tell why an evaluation failed
+type ErrorKind int
+
+const (
+	RuntimeError         ErrorKind = iota // the program did something invalid
+	CancelledError                        // the context of the evaluation was cancelled
+	BudgetExhaustedError                  // the evaluation took more steps than its budget
+)
+
+// Frame is a function call which an error propagated through
+type Frame struct {
+	Function string         // name of the called function, or "<anonymous>"
+	CallSite token.Position // position of the call expression
+}
+
+// String return "<function> called at <position>"
+func (f Frame) String() string {
+	return f.Function + " called at " + f.CallSite.String()
+}
+
+// maxTracebackFrames is the number of frames shown at each end of a long traceback
+const maxTracebackFrames = 10
+
+// Traceback return Inspect() followed by the call stack, innermost call first:
+//
+//	ERROR: 2:3: unknown operator: -BOOLEAN
+//	    inner called at 5:10
+//	    outer called at 8:1
+func (e *Error) Traceback() string {
+	var out bytes.Buffer
+
+	out.WriteString(e.Inspect())
+
+	for i, frame := range e.Stack {
+		if len(e.Stack) > 2*maxTracebackFrames && i == maxTracebackFrames {
+			out.WriteString(fmt.Sprintf("\n    ... %d more calls ...", len(e.Stack)-2*maxTracebackFrames))
+		}
+		if len(e.Stack) > 2*maxTracebackFrames && i >= maxTracebackFrames && i < len(e.Stack)-maxTracebackFrames {
+			continue
+		}
+		out.WriteString("\n    " + frame.String())
+	}
+
+	return out.String()
 }
 
-// Inspect return "ERROR: ~"
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+// Error return Inspect(), so that an Error can be used as a Go error
+func (e *Error) Error() string { return e.Inspect() }
+
+// Inspect return "ERROR: ~" or "ERROR: <position>: ~"
+func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return "ERROR: " + e.Pos.String() + ": " + e.Message
+	}
+	return "ERROR: " + e.Message
+}
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 
 // Function is from ast.FunctionLiteral
 type Function struct {
+	Name       string // name of the first let binding, or empty when anonymous
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
@@ -112,8 +222,42 @@ type String struct {
 func (s *String) Inspect() string  { return s.Value }
 func (s *String) Type() ObjectType { return STRING_OBJ }
 
-// BuiltinFunction is builtin function type
-type BuiltinFunction func(args ...Object) Object
+// BuiltinFunction is builtin function type.
+// stdio is where the evaluation which calls the builtin reads and writes.
+type BuiltinFunction func(stdio *IO, args ...Object) Object
+
+// IO is the standard streams of an evaluation
+type IO struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	reader *bufio.Reader
+}
+
+// standardIO is created with its reader, because Reader would create it without synchronization
+// when evaluations running concurrently share standardIO
+var standardIO = &IO{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr, reader: bufio.NewReader(os.Stdin)}
+
+// StandardIO return the IO of os.Stdin, os.Stdout and os.Stderr, which is shared by every evaluation
+func StandardIO() *IO {
+	return standardIO
+}
+
+// Reader return Stdin buffered. The buffer is kept by stdio, so that the reading builtins do not lose input between calls.
+func (stdio *IO) Reader() *bufio.Reader {
+	if stdio.reader == nil {
+		switch stdin := stdio.Stdin.(type) {
+		case *bufio.Reader:
+			stdio.reader = stdin
+		case nil:
+			stdio.reader = bufio.NewReader(strings.NewReader(""))
+		default:
+			stdio.reader = bufio.NewReader(stdin)
+		}
+	}
+	return stdio.reader
+}
 
 // Builtin is builtin function type
 type Builtin struct {
@@ -124,26 +268,23 @@ type Builtin struct {
 func (b *Builtin) Inspect() string  { return "builtin function" }
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
 
+// Iterator is a sequence whose items are produced one by one, while a for-in loop consumes them
+type Iterator struct {
+	// Next return the next item, and false when there is none left. An ERROR item stops the loop with that error.
+	Next func() (Object, bool)
+}
+
+// Inspect return "iterator"
+func (it *Iterator) Inspect() string  { return "iterator" }
+func (it *Iterator) Type() ObjectType { return ITERATOR_OBJ }
+
 // Array is from ast.ArrayLiteral
 type Array struct {
 	Elements []Object
 }
 
-// Inspect return "[...<elements>]"
-func (ao *Array) Inspect() string {
-	var out bytes.Buffer
-
-	elements := []string{}
-	for _, e := range ao.Elements {
-		elements = append(elements, e.Inspect())
-	}
-
-	out.WriteString("[")
-	out.WriteString(strings.Join(elements, ", "))
-	out.WriteString("]")
-
-	return out.String()
-}
+// Inspect return "[...<elements>]". An array which contains itself is shown as "[...]" inside.
+func (ao *Array) Inspect() string  { return inspect(ao, map[Object]bool{}) }
 func (ao *Array) Type() ObjectType { return ARRAY_OBJ }
 
 // HashKey is from ast.HashLiteral
@@ -170,6 +311,15 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+// HashKey return float to hash.
+// A float with an integral value has the same key as the equal integer, so that 1.0 and 1 are the same key.
+func (f *Float) HashKey() HashKey {
+	if f.Value == math.Trunc(f.Value) && math.Abs(f.Value) < 1<<63 {
+		return HashKey{Type: INTEGER_OBJ, Value: uint64(int64(f.Value))}
+	}
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
 // HashKey return string to hash
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
@@ -189,23 +339,68 @@ type Hash struct {
 	Pairs map[HashKey]HashPair
 }
 
-// Inspect return "{<key>: <value>, ...}"
-func (h *Hash) Inspect() string {
+// Inspect return "{<key>: <value>, ...}". A hash which contains itself is shown as "{...}" inside.
+func (h *Hash) Inspect() string { return inspect(h, map[Object]bool{}) }
+
+// inspect return Inspect() of obj, where the arrays and hashes of enclosing, which contain obj,
+// are shown as "[...]" and "{...}", so that a cycle created by an index assignment ends
+func inspect(obj Object, enclosing map[Object]bool) string {
 	var out bytes.Buffer
 
-	pairs := []string{}
-	for _, pair := range h.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
+	switch obj := obj.(type) {
+	case *Array:
+		if enclosing[obj] {
+			return "[...]"
+		}
+		enclosing[obj] = true
+		defer delete(enclosing, obj)
+
+		elements := []string{}
+		for _, e := range obj.Elements {
+			elements = append(elements, inspect(e, enclosing))
+		}
+
+		out.WriteString("[")
+		out.WriteString(strings.Join(elements, ", "))
+		out.WriteString("]")
+
+	case *Hash:
+		if enclosing[obj] {
+			return "{...}"
+		}
+		enclosing[obj] = true
+		defer delete(enclosing, obj)
+
+		pairs := []string{}
+		for _, pair := range obj.Pairs {
+			pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), inspect(pair.Value, enclosing)))
+		}
+
+		out.WriteString("{")
+		out.WriteString(strings.Join(pairs, ", "))
+		out.WriteString("}")
+
+	default:
+		return obj.Inspect()
 	}
 
-	out.WriteString("{")
-	out.WriteString(strings.Join(pairs, ", "))
-	out.WriteString("}")
-
 	return out.String()
 }
+
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
 
+// Keys return the keys of the hash in a stable order
+func (h *Hash) Keys() []Object {
+	keys := make([]Object, 0, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		keys = append(keys, pair.Key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Inspect() < keys[j].Inspect()
+	})
+	return keys
+}
+
 // Hashable is interface of Hash
 type Hashable interface {
 	HashKey() HashKey
@@ -248,3 +443,80 @@ func (m *Macro) Inspect() string {
 	return out.String()
 }
 func (m *Macro) Type() ObjectType { return MACRO_OBJ }
+
+// Module is a file imported by a program, whose exported top-level bindings are its members
+type Module struct {
+	Name    string // the name of the file without its directory and extension
+	Path    string // absolute path of the file
+	Env     *Environment
+	Exports map[string]bool // the names bound by "export let"
+}
+
+// Inspect return "<module name>"
+func (m *Module) Inspect() string  { return "<module " + m.Name + ">" }
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+
+// Get return the exported binding name of the module
+func (m *Module) Get(name string) (Object, bool) {
+	if !m.Exports[name] {
+		return nil, false
+	}
+	obj, ok := m.Env.store[name]
+	return obj, ok
+}
+
+// Private report whether name is a top-level binding of the module which is not exported
+func (m *Module) Private(name string) bool {
+	_, ok := m.Env.store[name]
+	return ok && !m.Exports[name]
+}
+
+// CompiledFunction is from ast.FunctionLiteral compiled to bytecode
+type CompiledFunction struct {
+	Name          string // name of the let binding, or empty when anonymous
+	Instructions  code.Instructions
+	NumLocals     int
+	NumParameters int
+	SourceMap     code.SourceMap
+	LocalNames    []string // names of the local variables, by index
+	FreeNames     []string // names of the captured variables, by index
+}
+
+// Inspect return "CompiledFunction[<address>]"
+func (cf *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", cf)
+}
+func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
+
+// Closure is a CompiledFunction with the variables it captured
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []*Upvalue
+}
+
+// Inspect return "Closure[<address>]"
+func (c *Closure) Inspect() string {
+	return fmt.Sprintf("Closure[%p]", c)
+}
+
+// Type return FUNCTION_OBJ, so that a closure is the same as a Function for scripts
+func (c *Closure) Type() ObjectType { return FUNCTION_OBJ }
+
+// Upvalue is a variable captured by a Closure.
+// While the function defining the variable runs, it points into the VM stack,
+// so that the closure and the function see each other's assignments.
+// When that function returns, the value is moved into the Upvalue itself.
+type Upvalue struct {
+	Slot   *Object
+	closed Object
+}
+
+// Close move the value out of the VM stack into the Upvalue
+func (u *Upvalue) Close() {
+	u.closed = *u.Slot
+	u.Slot = &u.closed
+}
+
+// Inspect return Inspect() of the captured value
+func (u *Upvalue) Inspect() string  { return (*u.Slot).Inspect() }
+func (u *Upvalue) Type() ObjectType { return UPVALUE_OBJ }
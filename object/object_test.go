@@ -1,6 +1,11 @@
 package object
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/NAKKA-K/learn-interpreter-in-go/token"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -20,3 +25,131 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with same content have different hash keys")
 	}
 }
+
+func TestFloatHashKey(t *testing.T) {
+	one := &Integer{Value: 1}
+	oneFloat := &Float{Value: 1.0}
+	half1 := &Float{Value: 0.5}
+	half2 := &Float{Value: 0.5}
+
+	if half1.HashKey() != half2.HashKey() {
+		t.Errorf("floats with same value have different hash keys")
+	}
+
+	if one.HashKey() != oneFloat.HashKey() {
+		t.Errorf("integral float has different hash key from equal integer")
+	}
+
+	if half1.HashKey() == oneFloat.HashKey() {
+		t.Errorf("floats with different value have same hash keys")
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{1.5, "1.5"},
+		{2, "2.0"},
+		{-3, "-3.0"},
+		{1e-9, "1e-09"},
+	}
+
+	for _, tt := range tests {
+		f := &Float{Value: tt.value}
+		if f.Inspect() != tt.expected {
+			t.Errorf("wrong Inspect. expected=%q, got=%q", tt.expected, f.Inspect())
+		}
+	}
+}
+
+func TestErrorTraceback(t *testing.T) {
+	err := &Error{
+		Message: "identifier not found: x",
+		Pos:     token.Position{Line: 2, Column: 3},
+		Stack: []Frame{
+			{Function: "inner", CallSite: token.Position{Line: 5, Column: 10}},
+			{Function: "<anonymous>", CallSite: token.Position{Line: 8, Column: 1}},
+		},
+	}
+
+	expected := "ERROR: 2:3: identifier not found: x\n" +
+		"    inner called at 5:10\n" +
+		"    <anonymous> called at 8:1"
+
+	if err.Traceback() != expected {
+		t.Errorf("wrong traceback.\nexpected=%q\ngot=     %q", expected, err.Traceback())
+	}
+}
+
+func TestLongErrorTracebackIsTruncated(t *testing.T) {
+	err := &Error{Message: "boom"}
+	for i := 0; i < 25; i++ {
+		err.Stack = append(err.Stack, Frame{Function: "f", CallSite: token.Position{Line: i + 1, Column: 1}})
+	}
+
+	lines := strings.Split(err.Traceback(), "\n")
+	if len(lines) != 1+2*maxTracebackFrames+1 {
+		t.Fatalf("wrong number of lines. got=%d", len(lines))
+	}
+	if lines[maxTracebackFrames+1] != "    ... 5 more calls ..." {
+		t.Errorf("wrong elision line. got=%q", lines[maxTracebackFrames+1])
+	}
+	if lines[len(lines)-1] != "    f called at 25:1" {
+		t.Errorf("wrong last line. got=%q", lines[len(lines)-1])
+	}
+}
+
+func TestInspectCycles(t *testing.T) {
+	array := &Array{Elements: []Object{&Integer{Value: 1}}}
+	array.Elements = append(array.Elements, array)
+
+	if array.Inspect() != "[1, [...]]" {
+		t.Errorf("wrong inspect of a cyclic array. got=%q", array.Inspect())
+	}
+
+	key := &String{Value: "self"}
+	hash := &Hash{Pairs: map[HashKey]HashPair{}}
+	hash.Pairs[key.HashKey()] = HashPair{Key: key, Value: &Array{Elements: []Object{hash}}}
+
+	if hash.Inspect() != "{self: [{...}]}" {
+		t.Errorf("wrong inspect of a cyclic hash. got=%q", hash.Inspect())
+	}
+
+	// an array contained twice, but not in itself, is shown twice
+	inner := &Array{Elements: []Object{&Integer{Value: 2}}}
+	twice := &Array{Elements: []Object{inner, inner}}
+	if twice.Inspect() != "[[2], [2]]" {
+		t.Errorf("wrong inspect of a shared array. got=%q", twice.Inspect())
+	}
+}
+
+func TestOperations(t *testing.T) {
+	hash := &Hash{Pairs: map[HashKey]HashPair{}}
+
+	tests := []struct {
+		result   Object
+		expected string
+	}{
+		{InfixOperation("+", &Integer{Value: 1}, &Integer{Value: 2}), "3"},
+		{InfixOperation("/", &Integer{Value: 7}, &Float{Value: 2}), "3.5"},
+		{InfixOperation("%", &Integer{Value: 1}, &Integer{Value: 0}), "ERROR: division by zero: 1 % 0"},
+		{InfixOperation("-", &String{Value: "a"}, &String{Value: "b"}), "ERROR: unknown operator: STRING - STRING"},
+		{InfixOperation("+", &Integer{Value: 1}, TRUE), "ERROR: type mismatch: INTEGER + BOOLEAN"},
+		{PrefixOperation("!", NULL), "true"},
+		{PrefixOperation("-", TRUE), "ERROR: unknown operator: -BOOLEAN"},
+		{Index(&String{Value: "héllo"}, &Integer{Value: 1}), "é"},
+		{Index(&Array{}, &Integer{Value: 0}), "null"},
+		{SetIndex(&Array{}, &Integer{Value: 0}, TRUE), "ERROR: index out of range: 0"},
+		{SetProperty(hash, "name", &String{Value: "Ann"}), "Ann"},
+		{Property(hash, "name"), "Ann"},
+		{Property(hash, "age"), `ERROR: hash has no key "age"`},
+	}
+
+	for i, tt := range tests {
+		if tt.result.Inspect() != tt.expected {
+			t.Errorf("tests[%d] - wrong result. expected=%q, got=%q", i, tt.expected, tt.result.Inspect())
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package object
+
+import "math"
+
+// The operations below are shared by the evaluator and the VM, so that both engines follow the same rules.
+// An operation which fails returns an *Error without position, which the engine attaches.
+
+// InfixOperation apply a binary operator other than "&&" and "||", which short-circuit
+func InfixOperation(operator string, left, right Object) Object {
+	switch {
+	case left.Type() == INTEGER_OBJ && right.Type() == INTEGER_OBJ:
+		return integerOperation(operator, left.(*Integer).Value, right.(*Integer).Value)
+	case isNumber(left) && isNumber(right):
+		return floatOperation(operator, toFloat(left), toFloat(right))
+	case left.Type() == STRING_OBJ && right.Type() == STRING_OBJ && operator == "+":
+		return &String{Value: left.(*String).Value + right.(*String).Value}
+	case left.Type() == STRING_OBJ && right.Type() == STRING_OBJ:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	case operator == "==":
+		return nativeBool(left == right)
+	case operator == "!=":
+		return nativeBool(left != right)
+	case left.Type() != right.Type():
+		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func integerOperation(operator string, leftVal, rightVal int64) Object {
+	switch operator {
+	case "+":
+		return &Integer{Value: leftVal + rightVal}
+	case "-":
+		return &Integer{Value: leftVal - rightVal}
+	case "*":
+		return &Integer{Value: leftVal * rightVal}
+	case "/":
+		if rightVal == 0 {
+			return newError("division by zero: %d / %d", leftVal, rightVal)
+		}
+		return &Integer{Value: leftVal / rightVal}
+	case "%":
+		if rightVal == 0 {
+			return newError("division by zero: %d %% %d", leftVal, rightVal)
+		}
+		return &Integer{Value: leftVal % rightVal}
+	case "<":
+		return nativeBool(leftVal < rightVal)
+	case ">":
+		return nativeBool(leftVal > rightVal)
+	case "<=":
+		return nativeBool(leftVal <= rightVal)
+	case ">=":
+		return nativeBool(leftVal >= rightVal)
+	case "==":
+		return nativeBool(leftVal == rightVal)
+	case "!=":
+		return nativeBool(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", INTEGER_OBJ, operator, INTEGER_OBJ)
+	}
+}
+
+// floatOperation apply operator to two numbers of which at least one is FLOAT.
+// An INTEGER operand is promoted to FLOAT, and the result of arithmetic is always FLOAT.
+func floatOperation(operator string, leftVal, rightVal float64) Object {
+	switch operator {
+	case "+":
+		return &Float{Value: leftVal + rightVal}
+	case "-":
+		return &Float{Value: leftVal - rightVal}
+	case "*":
+		return &Float{Value: leftVal * rightVal}
+	case "/":
+		return &Float{Value: leftVal / rightVal}
+	case "%":
+		return &Float{Value: math.Mod(leftVal, rightVal)}
+	case "<":
+		return nativeBool(leftVal < rightVal)
+	case ">":
+		return nativeBool(leftVal > rightVal)
+	case "<=":
+		return nativeBool(leftVal <= rightVal)
+	case ">=":
+		return nativeBool(leftVal >= rightVal)
+	case "==":
+		return nativeBool(leftVal == rightVal)
+	case "!=":
+		return nativeBool(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", FLOAT_OBJ, operator, FLOAT_OBJ)
+	}
+}
+
+// PrefixOperation apply "!" or "-"
+func PrefixOperation(operator string, right Object) Object {
+	switch operator {
+	case "!":
+		return nativeBool(!IsTruthy(right))
+	case "-":
+		switch right := right.(type) {
+		case *Integer:
+			return &Integer{Value: -right.Value}
+		case *Float:
+			return &Float{Value: -right.Value}
+		default:
+			return newError("unknown operator: -%s", right.Type())
+		}
+	default:
+		return newError("unknown operator: %s%s", operator, right.Type())
+	}
+}
+
+// IsTruthy report whether obj counts as true in a condition: anything but NULL and FALSE
+func IsTruthy(obj Object) bool {
+	return obj != NULL && obj != FALSE
+}
+
+// Index return left[index]. An index out of range or a missing key is NULL.
+func Index(left, index Object) Object {
+	switch left := left.(type) {
+	case *Array:
+		idx, ok := index.(*Integer)
+		if !ok {
+			break
+		}
+		if idx.Value < 0 || idx.Value >= int64(len(left.Elements)) {
+			return NULL
+		}
+		return left.Elements[idx.Value]
+
+	case *String:
+		idx, ok := index.(*Integer)
+		if !ok {
+			break
+		}
+		runes := []rune(left.Value)
+		if idx.Value < 0 || idx.Value >= int64(len(runes)) {
+			return NULL
+		}
+		return &String{Value: string(runes[idx.Value])}
+
+	case *Hash:
+		key, ok := index.(Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+		pair, ok := left.Pairs[key.HashKey()]
+		if !ok {
+			return NULL
+		}
+		return pair.Value
+	}
+
+	return newError("index operator not supported: %s", left.Type())
+}
+
+// SetIndex set left[index] to val and return val. An array index must be in range.
+func SetIndex(left, index, val Object) Object {
+	switch left := left.(type) {
+	case *Array:
+		idx, ok := index.(*Integer)
+		if !ok {
+			return newError("array index must be INTEGER, got %s", index.Type())
+		}
+		if idx.Value < 0 || idx.Value >= int64(len(left.Elements)) {
+			return newError("index out of range: %d", idx.Value)
+		}
+		left.Elements[idx.Value] = val
+		return val
+
+	case *Hash:
+		key, ok := index.(Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+		left.Pairs[key.HashKey()] = HashPair{Key: index, Value: val}
+		return val
+
+	default:
+		return newError("index assignment not supported: %s", left.Type())
+	}
+}
+
+// Property return the value of the key name of a hash. Unlike Index, a missing key is an error.
+func Property(left Object, name string) Object {
+	hash, ok := left.(*Hash)
+	if !ok {
+		return newError("property access not supported: %s", left.Type())
+	}
+	pair, ok := hash.Pairs[(&String{Value: name}).HashKey()]
+	if !ok {
+		return newError("hash has no key %q", name)
+	}
+	return pair.Value
+}
+
+// SetProperty set the key name of a hash, which is added when it is missing, and return val
+func SetProperty(left Object, name string, val Object) Object {
+	hash, ok := left.(*Hash)
+	if !ok {
+		return newError("property assignment not supported: %s", left.Type())
+	}
+	key := &String{Value: name}
+	hash.Pairs[key.HashKey()] = HashPair{Key: key, Value: val}
+	return val
+}
+
+func nativeBool(input bool) *Boolean {
+	if input {
+		return TRUE
+	}
+	return FALSE
+}
+
+func isNumber(obj Object) bool {
+	return obj.Type() == INTEGER_OBJ || obj.Type() == FLOAT_OBJ
+}
+
+func toFloat(obj Object) float64 {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value)
+	case *Float:
+		return obj.Value
+	default:
+		return 0
+	}
+}
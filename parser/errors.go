@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/NAKKA-K/learn-interpreter-in-go/token"
+)
+
+// ParseError is a syntax error found while parsing
+type ParseError struct {
+	Pos      token.Position
+	Expected token.TokenType // the token kind the parser wanted, if any
+	Actual   token.TokenType // the token kind the parser found
+	Msg      string
+	Hint     string // suggestion for fixing the error, if any
+}
+
+// Error return "<position>: <message>"
+func (e *ParseError) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// Render return the error with the offending source line and a caret under the position:
+//
+//	1:9: no prefix parse function for = found
+//	    let x = = 5;
+//	            ^
+//	    hint: an expression cannot start with '='
+func (e *ParseError) Render(source string) string {
+	var out bytes.Buffer
+
+	out.WriteString(e.Error())
+	out.WriteString("\n")
+
+	if line, ok := sourceLine(source, e.Pos.Line); ok {
+		out.WriteString("    " + line + "\n")
+		out.WriteString("    " + caretPadding(line, e.Pos.Column) + "^\n")
+	}
+
+	if e.Hint != "" {
+		out.WriteString("    hint: " + e.Hint + "\n")
+	}
+
+	return out.String()
+}
+
+// sourceLine return the n-th line (starting at 1) of source
+func sourceLine(source string, n int) (string, bool) {
+	if n < 1 {
+		return "", false
+	}
+
+	lines := strings.Split(source, "\n")
+	if n > len(lines) {
+		return "", false
+	}
+
+	return strings.TrimRight(lines[n-1], "\r"), true
+}
+
+// caretPadding return the blanks which put a caret under column (in runes) of line.
+// Tabs are kept so that the caret lines up however the terminal expands them.
+func caretPadding(line string, column int) string {
+	var out bytes.Buffer
+
+	i := 1
+	for _, r := range line {
+		if i >= column {
+			break
+		}
+		if r == '\t' {
+			out.WriteRune('\t')
+		} else {
+			out.WriteRune(' ')
+		}
+		i++
+	}
+
+	for ; i < column; i++ {
+		out.WriteRune(' ')
+	}
+
+	return out.String()
+}
+
+var expectHints = map[token.TokenType]string{
+	token.IDENT:     "a name is required here",
+	token.ASSIGN:    "a let statement has the form 'let <name> = <value>;'",
+	token.COLON:     "a hash pair has the form '<key>: <value>'",
+	token.SEMICOLON: "is a ';' missing?",
+	token.LPAREN:    "is a '(' missing?",
+	token.RPAREN:    "is a ')' missing?",
+	token.LBRACE:    "a body must be enclosed in '{' and '}'",
+	token.RBRACE:    "is a '}' missing?",
+	token.RBRACKET:  "is a ']' missing?",
+	token.STRING:    "an import has the form 'import \"<path>\";'",
+}
+
+func expectHint(expected token.TokenType) string {
+	return expectHints[expected]
+}
+
+func noPrefixHint(t token.Token) string {
+	switch t.Type {
+	case token.EOF:
+		return "the input ended in the middle of an expression"
+	case token.ILLEGAL:
+		return "'" + t.Literal + "' is not a valid character here"
+	default:
+		return "an expression cannot start with '" + t.Literal + "'"
+	}
+}
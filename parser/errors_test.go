@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/NAKKA-K/learn-interpreter-in-go/lexer"
+	"github.com/NAKKA-K/learn-interpreter-in-go/token"
+)
+
+func TestParseErrorFields(t *testing.T) {
+	l := lexer.New("let x 5;")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.ParseErrors()
+	if len(errors) != 1 {
+		t.Fatalf("wrong number of errors. expected=1, got=%q", p.Errors())
+	}
+
+	err := errors[0]
+	if err.Pos.String() != "1:7" {
+		t.Errorf("err.Pos wrong. expected=1:7, got=%s", err.Pos)
+	}
+	if err.Expected != token.ASSIGN {
+		t.Errorf("err.Expected wrong. expected=%q, got=%q", token.ASSIGN, err.Expected)
+	}
+	if err.Actual != token.INT {
+		t.Errorf("err.Actual wrong. expected=%q, got=%q", token.INT, err.Actual)
+	}
+	if err.Hint == "" {
+		t.Errorf("err.Hint is empty")
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors []string
+		expectedStmts  int
+	}{
+		{
+			"let x = = 5; let y = 1;",
+			[]string{"1:9: no prefix parse function for = found"},
+			1,
+		},
+		{
+			"let = 1; let y = (1 + ; let z = 3;",
+			[]string{
+				"1:5: expected next token to be IDENT, got = insted",
+				"1:23: no prefix parse function for ; found",
+			},
+			1,
+		},
+		{
+			"let f = fn(x) { let = 1; x }; f(1);",
+			[]string{"1:21: expected next token to be IDENT, got = insted"},
+			2,
+		},
+		{
+			"if (x) { let y = ; } let z = 1;",
+			[]string{"1:18: no prefix parse function for ; found"},
+			2,
+		},
+		{
+			"let f = fn(x) { x",
+			[]string{"1:18: expected token to be }, got EOF insted"},
+			0,
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("wrong number of errors for %q. expected=%q, got=%q", tt.input, tt.expectedErrors, errors)
+			continue
+		}
+		for i, msg := range tt.expectedErrors {
+			if errors[i] != msg {
+				t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, msg, errors[i])
+			}
+		}
+
+		if len(program.Statements) != tt.expectedStmts {
+			t.Errorf("wrong number of statements for %q. expected=%d, got=%d",
+				tt.input, tt.expectedStmts, len(program.Statements))
+		}
+	}
+}
+
+func TestParseErrorRender(t *testing.T) {
+	input := "let a = 1;\n\tlet b = (2 + 3;"
+
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.ParseErrors()
+	if len(errors) != 1 {
+		t.Fatalf("wrong number of errors. expected=1, got=%q", p.Errors())
+	}
+
+	expected := "2:16: expected next token to be ), got ; insted\n" +
+		"    \tlet b = (2 + 3;\n" +
+		"    \t              ^\n" +
+		"    hint: is a ')' missing?\n"
+
+	if errors[0].Render(input) != expected {
+		t.Errorf("wrong rendering.\nexpected=%q\ngot=     %q", expected, errors[0].Render(input))
+	}
+}
@@ -129,6 +129,41 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14;", 3.14},
+		{"1e-9;", 1e-9},
+		{"2.5E+3;", 2500},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %g. got=%g", tt.expected, literal.Value)
+		}
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -184,6 +219,11 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 < 5;", 5, "<", 5},
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5;", 5, "!=", 5},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"5 % 5;", 5, "%", 5},
+		{"true && false", true, "&&", false},
+		{"a || b", "a", "||", "b"},
 		{"true == true", true, "==", true},
 		{"true != false", true, "!=", false},
 		{"false == false", false, "==", false},
@@ -247,6 +287,26 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"a + b * c + d / e - f",
 			"(((a + (b * c)) + (d / e)) - f)",
 		},
+		{
+			"a + b % c",
+			"(a + (b % c))",
+		},
+		{
+			"a <= b == c >= d",
+			"((a <= b) == (c >= d))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a == b && c < d || !e",
+			"(((a == b) && (c < d)) || (!e))",
+		},
+		{
+			"x = a || b",
+			"x = (a || b)",
+		},
 		{
 			"3 + 4; -5 * 5",
 			"(3 + 4)((-5) * 5)",
@@ -644,6 +704,35 @@ func TestParsingIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestParsingPropertyExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"lib.name", "(lib.name)"},
+		{"a.b.c", "((a.b).c)"},
+		{"lib.double(2) + 1", "((lib.double)(2) + 1)"},
+		{"-lib.x", "(-(lib.x))"},
+		{"a[0].b", "((a[0]).b)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	p := New(lexer.New("lib.1"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("a property which is not a name is accepted")
+	}
+}
+
 func TestParsingHashLiteralsStringKeys(t *testing.T) {
 	input := `{"one": 1, "two": 2, "three": 3}`
 
@@ -746,6 +835,310 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 	}
 }
 
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 5;", "x = 5"},
+		{"x += y * 2;", "x += (y * 2)"},
+		{"x = y = 1;", "x = y = 1"},
+		{"arr[0] -= 1;", "(arr[0]) -= 1"},
+		{`h["k"] /= 2;`, "(h[k]) /= 2"},
+		{"x *= 1 + 2 == 3;", "x *= ((1 + 2) == 3)"},
+		{"h.name = 1;", "(h.name) = 1"},
+		{"h.a.b += 2;", "((h.a).b) += 2"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		if _, ok := stmt.Expression.(*ast.AssignExpression); !ok {
+			t.Fatalf("stmt.Expression is not ast.AssignExpression. got=%T", stmt.Expression)
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestInvalidAssignmentTarget(t *testing.T) {
+	l := lexer.New("1 + 2 = 3;")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 {
+		t.Fatalf("expected parser errors")
+	}
+	if errors[0] != "1:7: cannot assign to (1 + 2)" {
+		t.Errorf("wrong error. got=%q", errors[0])
+	}
+}
+
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < y) { x }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.WhileStatement. got=%T", program.Statements[0])
+	}
+
+	if !testInfixExpression(t, stmt.Condition, "x", "<", "y") {
+		return
+	}
+
+	if len(stmt.Body.Statements) != 1 {
+		t.Fatalf("body is not 1 statements. got=%d", len(stmt.Body.Statements))
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for (let i = 0; i < 10; let i = i + 1) { puts(i); }", "for (let i = 0; (i < 10); let i = (i + 1)) puts(i)"},
+		{"for (;;) { break; }", "for (; ; ) break;"},
+		{"for (; i < 10;) { continue }", "for (; (i < 10); ) continue;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ForStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ForStatement. got=%T", program.Statements[0])
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestForInStatement(t *testing.T) {
+	input := `for (x in [1, 2]) { x }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ForInStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ForInStatement. got=%T", program.Statements[0])
+	}
+
+	if !testIdentifier(t, stmt.Variable, "x") {
+		return
+	}
+
+	array, ok := stmt.Iterable.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("stmt.Iterable is not ast.ArrayLiteral. got=%T", stmt.Iterable)
+	}
+	if len(array.Elements) != 2 {
+		t.Fatalf("len(array.Elements) not 2. got=%d", len(array.Elements))
+	}
+}
+
+func TestImportStatement(t *testing.T) {
+	input := `import "lib/math"; import "util"`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+	for i, path := range []string{"lib/math", "util"} {
+		stmt, ok := program.Statements[i].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("program.Statements[%d] is not ast.ImportStatement. got=%T", i, program.Statements[i])
+		}
+		if stmt.Path.Value != path {
+			t.Errorf("stmt.Path.Value not %q. got=%q", path, stmt.Path.Value)
+		}
+	}
+	if program.String() != `import "lib/math";import "util";` {
+		t.Errorf("wrong String(). got=%q", program.String())
+	}
+
+	p = New(lexer.New("import lib;"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("an import without a path string is accepted")
+	}
+}
+
+func TestImportForms(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		alias    string
+		names    []string
+	}{
+		{`import "lib/math" as m`, `import "lib/math" as m;`, "m", nil},
+		{`import { a } from "x";`, `import { a } from "x";`, "", []string{"a"}},
+		{`import { a, b, c } from "x"`, `import { a, b, c } from "x";`, "", []string{"a", "b", "c"}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ImportStatement. got=%T", program.Statements[0])
+		}
+		if stmt.String() != tt.expected {
+			t.Errorf("wrong String(). expected=%q, got=%q", tt.expected, stmt.String())
+		}
+		if tt.alias != "" && (stmt.Alias == nil || stmt.Alias.Value != tt.alias) {
+			t.Errorf("wrong alias. expected=%q, got=%v", tt.alias, stmt.Alias)
+		}
+		if len(stmt.Names) != len(tt.names) {
+			t.Fatalf("wrong number of names. expected=%d, got=%d", len(tt.names), len(stmt.Names))
+		}
+		for i, name := range tt.names {
+			testIdentifier(t, stmt.Names[i], name)
+		}
+	}
+
+	for _, input := range []string{
+		`import {} from "x"`,
+		`import { a } "x"`,
+		`import { a, } from "x"`,
+		`import { a } from "x" as y`,
+		`import "x" as "y"`,
+	} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q is accepted", input)
+		}
+	}
+}
+
+func TestExportStatement(t *testing.T) {
+	p := New(lexer.New("export let x = 1; let y = 2;"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	for i, exported := range []bool{true, false} {
+		stmt, ok := program.Statements[i].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("program.Statements[%d] is not ast.LetStatement. got=%T", i, program.Statements[i])
+		}
+		if stmt.Exported != exported {
+			t.Errorf("program.Statements[%d].Exported is not %t", i, exported)
+		}
+	}
+	if program.String() != "export let x = 1;let y = 2;" {
+		t.Errorf("wrong String(). got=%q", program.String())
+	}
+	if pos := program.Statements[0].Pos(); pos.Column != 1 {
+		t.Errorf("an exported let does not start at export. got=%s", pos)
+	}
+
+	for _, input := range []string{
+		"export x;",
+		"fn() { export let x = 1; }",
+		"if (true) { export let x = 1; }",
+	} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q is accepted", input)
+		}
+	}
+}
+
+func TestLoopFollowedBySemicolon(t *testing.T) {
+	tests := []struct {
+		input    string
+		loopType string
+	}{
+		{"while (i < 5) { i = i + 1 }; i", "*ast.WhileStatement"},
+		{"for (let i = 0; i < 5; i += 1) { x += i }; x", "*ast.ForStatement"},
+		{"for (x in [1, 2]) { total += x }; total", "*ast.ForInStatement"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 2 {
+			t.Fatalf("program.Statements does not contain 2 statements for %q. got=%d", tt.input, len(program.Statements))
+		}
+		if loopType := fmt.Sprintf("%T", program.Statements[0]); loopType != tt.loopType {
+			t.Errorf("program.Statements[0] is not %s. got=%s", tt.loopType, loopType)
+		}
+		if _, ok := program.Statements[1].(*ast.ExpressionStatement); !ok {
+			t.Errorf("program.Statements[1] is not ast.ExpressionStatement. got=%T", program.Statements[1])
+		}
+	}
+}
+
+func TestLoopControlOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"break;", "1:1: break outside loop"},
+		{"if (true) { continue; }", "1:13: continue outside loop"},
+		{"while (true) { fn() { break; } }", "1:23: break outside loop"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("wrong number of errors. expected=1, got=%q", errors)
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
 func TestMacroLiteralParsing(t *testing.T) {
 	input := `macro(x, y) { x + y; }`
 
@@ -920,3 +1313,62 @@ func testBooleanLiteral(t *testing.T, exp ast.Expression, value bool) bool {
 
 	return true
 }
+
+func TestNodePositions(t *testing.T) {
+	input := `let add = fn(x, y) {
+  x + y;
+};
+add(1, [2, 3][0]);`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	tests := []struct {
+		node          ast.Node
+		expectedStart string
+		expectedEnd   string
+	}{
+		{program, "1:1", "4:18"},
+		{program.Statements[0], "1:1", "3:2"},
+		{program.Statements[0].(*ast.LetStatement).Value, "1:11", "3:2"},
+		{program.Statements[1], "4:1", "4:18"},
+	}
+
+	call := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	tests = append(tests, []struct {
+		node          ast.Node
+		expectedStart string
+		expectedEnd   string
+	}{
+		{call.Arguments[0], "4:5", "4:6"},
+		{call.Arguments[1], "4:8", "4:17"},
+		{call.Arguments[1].(*ast.IndexExpression).Left, "4:8", "4:14"},
+	}...)
+
+	for i, tt := range tests {
+		if tt.node.Pos().String() != tt.expectedStart {
+			t.Errorf("tests[%d] - start wrong. expected=%s, got=%s",
+				i, tt.expectedStart, tt.node.Pos())
+		}
+		if tt.node.End().String() != tt.expectedEnd {
+			t.Errorf("tests[%d] - end wrong. expected=%s, got=%s",
+				i, tt.expectedEnd, tt.node.End())
+		}
+	}
+}
+
+func TestLexerErrorsAreReported(t *testing.T) {
+	l := lexer.New("let x = 1; /* unterminated")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("wrong number of errors. expected=1, got=%d: %q", len(errors), errors)
+	}
+	if errors[0] != "1:12: unterminated block comment" {
+		t.Fatalf("wrong error. got=%q", errors[0])
+	}
+}
@@ -0,0 +1,230 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NAKKA-K/learn-interpreter-in-go/ast"
+	"github.com/NAKKA-K/learn-interpreter-in-go/lexer"
+	"github.com/NAKKA-K/learn-interpreter-in-go/object"
+	"github.com/NAKKA-K/learn-interpreter-in-go/token"
+)
+
+// command is a line of the REPL starting with ':', like ":load file.mk"
+type command struct {
+	name string
+	args string // the argument, as shown by :help
+	help string
+	run  func(s *session, arg string) (quit bool)
+}
+
+var commands []command
+
+// commands is initialized by init, because :help refers to it
+func init() {
+	commands = []command{
+		{"help", "", "show this help", (*session).help},
+		{"quit", "", "leave the REPL", func(s *session, arg string) bool { return true }},
+		{"load", "file", "run the program in file", (*session).load},
+		{"env", "", "list the bindings of the session", (*session).listBindings},
+		{"macros", "", "list the macros of the session", (*session).listMacros},
+		{"ast", "expr", "print the syntax tree of expr", (*session).printAST},
+		{"tokens", "expr", "print the tokens of expr", (*session).printTokens},
+		{"type", "expr", "print the type of the value of expr", (*session).printType},
+		{"time", "expr", "evaluate expr and print how long it took", (*session).timeEval},
+		{"reset", "", "forget every binding, macro and imported module", (*session).resetCommand},
+	}
+}
+
+// command run line, which starts with ':', and report whether the REPL has to quit
+func (s *session) command(line string) bool {
+	fields := strings.SplitN(strings.TrimPrefix(line, ":"), " ", 2)
+	name := fields[0]
+	arg := ""
+	if len(fields) == 2 {
+		arg = strings.TrimSpace(fields[1])
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		if cmd.args != "" && arg == "" {
+			fmt.Fprintf(s.out, "usage: :%s %s\n", cmd.name, cmd.args)
+			return false
+		}
+		return cmd.run(s, arg)
+	}
+
+	fmt.Fprintf(s.out, "unknown command :%s, type :help for the list of commands\n", name)
+	return false
+}
+
+func (s *session) help(arg string) bool {
+	for _, cmd := range commands {
+		fmt.Fprintf(s.out, "  %-14s %s\n", strings.TrimSpace(":"+cmd.name+" "+cmd.args), cmd.help)
+	}
+	io.WriteString(s.out, "Statements may span several lines. Ctrl-C or two blank lines discard an incomplete statement.\n")
+	if s.engine == EngineVM {
+		io.WriteString(s.out, "import is not supported by the vm engine; start the REPL with -engine=eval to use modules.\n")
+	}
+	return false
+}
+
+func (s *session) load(filename string) bool {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+		return false
+	}
+
+	s.run(filename, string(src))
+	return false
+}
+
+func (s *session) listBindings(arg string) bool {
+	bindings := s.bindings()
+
+	names := make([]string, 0, len(bindings))
+	for name := range bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(s.out, "%s = %s\n", name, s.printer.format(bindings[name], 0))
+	}
+	return false
+}
+
+func (s *session) listMacros(arg string) bool {
+	for _, name := range s.macroEnv.Names() {
+		obj, _ := s.macroEnv.Get(name)
+		macro, ok := obj.(*object.Macro)
+		if !ok {
+			continue
+		}
+
+		params := []string{}
+		for _, p := range macro.Parameters {
+			params = append(params, p.String())
+		}
+		fmt.Fprintf(s.out, "%s(%s)\n", name, strings.Join(params, ", "))
+	}
+	return false
+}
+
+func (s *session) printAST(source string) bool {
+	program, ok := s.parse("", source)
+	if !ok {
+		return false
+	}
+
+	var out strings.Builder
+	dumpTree(&out, program, "", 0)
+	io.WriteString(s.out, out.String())
+	return false
+}
+
+func (s *session) printTokens(source string) bool {
+	l := lexer.New(source)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		fmt.Fprintf(s.out, "%-6s %-10s %q\n", tok.Pos, tok.Type, tok.Literal)
+	}
+	for _, err := range l.Errors() {
+		fmt.Fprintln(s.out, err)
+	}
+	return false
+}
+
+func (s *session) printType(source string) bool {
+	evaluated, ok := s.eval("", source)
+	if ok && evaluated != nil {
+		fmt.Fprintln(s.out, evaluated.Type())
+	}
+	return false
+}
+
+func (s *session) timeEval(source string) bool {
+	start := time.Now()
+	s.run("", source)
+	fmt.Fprintf(s.out, "time: %s\n", time.Since(start))
+	return false
+}
+
+func (s *session) resetCommand(arg string) bool {
+	s.reset()
+	io.WriteString(s.out, "session reset\n")
+	return false
+}
+
+var nodeType = reflect.TypeOf((*ast.Node)(nil)).Elem()
+
+// dumpTree write node and its children as an indented tree, one node per line:
+//
+//	Program 1:1
+//	  Statements: ExpressionStatement 1:1
+//	    Expression: InfixExpression Operator="+" 1:1
+//	      Left: IntegerLiteral Value=1 1:1
+//	      Right: IntegerLiteral Value=2 1:5
+func dumpTree(out *strings.Builder, node ast.Node, label string, depth int) {
+	v := reflect.ValueOf(node)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	t := v.Type()
+
+	out.WriteString(strings.Repeat("  ", depth))
+	if label != "" {
+		out.WriteString(label + ": ")
+	}
+	out.WriteString(t.Name())
+
+	for i := 0; i < t.NumField(); i++ {
+		switch f := v.Field(i); f.Kind() {
+		case reflect.String:
+			fmt.Fprintf(out, " %s=%q", t.Field(i).Name, f.String())
+		case reflect.Int64, reflect.Float64, reflect.Bool:
+			fmt.Fprintf(out, " %s=%v", t.Field(i).Name, f.Interface())
+		}
+	}
+	if pos := node.Pos(); pos.IsValid() {
+		out.WriteString(" " + pos.String())
+	}
+	out.WriteString("\n")
+
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Name
+		f := v.Field(i)
+
+		switch {
+		case f.Type().Implements(nodeType):
+			if !f.IsNil() {
+				dumpTree(out, f.Interface().(ast.Node), name, depth+1)
+			}
+
+		case f.Kind() == reflect.Slice && f.Type().Elem().Implements(nodeType):
+			for j := 0; j < f.Len(); j++ {
+				dumpTree(out, f.Index(j).Interface().(ast.Node), name, depth+1)
+			}
+
+		case f.Kind() == reflect.Map && f.Type().Key().Implements(nodeType):
+			keys := f.MapKeys()
+			sort.Slice(keys, func(a, b int) bool {
+				return keys[a].Interface().(ast.Node).Pos().Offset < keys[b].Interface().(ast.Node).Pos().Offset
+			})
+			for _, key := range keys {
+				dumpTree(out, key.Interface().(ast.Node), name+" key", depth+1)
+				dumpTree(out, f.MapIndex(key).Interface().(ast.Node), name+" value", depth+1)
+			}
+		}
+	}
+}
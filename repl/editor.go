@@ -0,0 +1,489 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ErrInterrupted is returned by ReadLine when Ctrl-C is pressed
+var ErrInterrupted = errors.New("interrupted")
+
+// maxHistory is the number of lines the history keeps
+const maxHistory = 1000
+
+// lineReader read the lines typed in the REPL
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+// plainReader read lines without editing, when the input is not a terminal
+type plainReader struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func (r *plainReader) ReadLine(prompt string) (string, error) {
+	io.WriteString(r.out, prompt)
+
+	line, err := r.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// editor is a line editor for terminals, with cursor movement, history, reverse search and completion
+type editor struct {
+	in        *bufio.Reader
+	out       io.Writer
+	raw       func() (restore func(), err error) // put the terminal into raw mode while a line is edited
+	complete  func(prefix string) []string       // candidates for completing the word before the cursor
+	highlight func(line string) string           // colour the line, unless it is nil
+
+	history     []string
+	historyFile string // every new history entry is appended to it, unless it is empty
+
+	// the line being edited
+	prompt  string
+	line    []rune
+	pos     int    // cursor, as an index in line
+	histPos int    // index of the history entry shown, len(history) for the typed line
+	typed   []rune // the typed line, while history entries are shown
+	lastTab bool   // whether the previous key was a tab, so that a second tab lists the candidates
+}
+
+func newEditor(in *bufio.Reader, out io.Writer, raw func() (func(), error), complete func(string) []string) *editor {
+	return &editor{in: in, out: out, raw: raw, complete: complete}
+}
+
+// ctrl return the character typed with the control key and key
+func ctrl(key rune) rune { return key & 0x1f }
+
+// ReadLine read a line, which is edited in the terminal until Enter is pressed.
+// Ctrl-D on an empty line returns io.EOF, and Ctrl-C returns ErrInterrupted.
+func (e *editor) ReadLine(prompt string) (string, error) {
+	restore, err := e.raw()
+	if err != nil {
+		return (&plainReader{in: e.in, out: e.out}).ReadLine(prompt)
+	}
+	defer restore()
+
+	e.prompt, e.line, e.pos = prompt, nil, 0
+	e.histPos, e.typed = len(e.history), nil
+	e.lastTab = false
+	e.refresh()
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			if err == io.EOF && len(e.line) > 0 {
+				return e.accept(), nil
+			}
+			return "", err
+		}
+
+		tab := false
+		switch r {
+		case '\r', '\n':
+			return e.accept(), nil
+		case ctrl('C'):
+			io.WriteString(e.out, "^C\r\n")
+			return "", ErrInterrupted
+		case ctrl('D'):
+			if len(e.line) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			e.deleteRune()
+		case ctrl('A'):
+			e.pos = 0
+		case ctrl('E'):
+			e.pos = len(e.line)
+		case ctrl('B'):
+			e.moveLeft()
+		case ctrl('F'):
+			e.moveRight()
+		case ctrl('H'), 127:
+			if e.pos > 0 {
+				e.pos--
+				e.deleteRune()
+			}
+		case ctrl('K'):
+			e.line = e.line[:e.pos]
+		case ctrl('U'):
+			e.line = append([]rune{}, e.line[e.pos:]...)
+			e.pos = 0
+		case ctrl('W'):
+			e.deleteWord()
+		case ctrl('L'):
+			io.WriteString(e.out, "\x1b[H\x1b[2J")
+		case ctrl('P'):
+			e.showHistory(e.histPos - 1)
+		case ctrl('N'):
+			e.showHistory(e.histPos + 1)
+		case ctrl('R'):
+			if e.search() {
+				return e.accept(), nil
+			}
+		case '\t':
+			e.completeWord()
+			tab = true
+		case 0x1b:
+			e.escape()
+		default:
+			if unicode.IsPrint(r) {
+				e.insert(r)
+			}
+		}
+
+		e.lastTab = tab
+		e.refresh()
+	}
+}
+
+// accept end the edition of the line, and add it to the history
+func (e *editor) accept() string {
+	e.pos = len(e.line)
+	e.refresh()
+	io.WriteString(e.out, "\r\n")
+
+	line := string(e.line)
+	e.addHistory(line)
+	return line
+}
+
+// refresh redraw the prompt and the line, and put the cursor at its position.
+// The cursor is moved back by the columns the runes after it take in the terminal, see runeWidth.
+func (e *editor) refresh() {
+	var out strings.Builder
+	out.WriteString("\r")
+	out.WriteString(e.prompt)
+	if e.highlight != nil {
+		out.WriteString(e.highlight(string(e.line)))
+	} else {
+		out.WriteString(string(e.line))
+	}
+	out.WriteString("\x1b[K")
+	if back := displayWidth(e.line[e.pos:]); back > 0 {
+		fmt.Fprintf(&out, "\x1b[%dD", back)
+	}
+	io.WriteString(e.out, out.String())
+}
+
+// displayWidth return the number of terminal columns runes take
+func displayWidth(runes []rune) int {
+	width := 0
+	for _, r := range runes {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// runeWidth return the number of terminal columns r takes: 0 for a combining mark or a zero width character,
+// 2 for an East Asian wide or fullwidth character, like CJK ideographs, Hangul syllables and emoji, and 1 otherwise.
+// The wide ranges are the common ones of Unicode's EastAsianWidth.txt rather than the full table.
+func runeWidth(r rune) int {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case r >= 0x1100 && r <= 0x115f, // Hangul Jamo
+		r >= 0x2e80 && r <= 0x303e,   // CJK radicals, Kangxi radicals, CJK symbols and punctuation
+		r >= 0x3041 && r <= 0x33ff,   // Hiragana, Katakana, Bopomofo, CJK compatibility
+		r >= 0x3400 && r <= 0x4dbf,   // CJK unified ideographs extension A
+		r >= 0x4e00 && r <= 0x9fff,   // CJK unified ideographs
+		r >= 0xa000 && r <= 0xa4cf,   // Yi
+		r >= 0xac00 && r <= 0xd7a3,   // Hangul syllables
+		r >= 0xf900 && r <= 0xfaff,   // CJK compatibility ideographs
+		r >= 0xfe30 && r <= 0xfe4f,   // CJK compatibility forms
+		r >= 0xff00 && r <= 0xff60,   // fullwidth forms
+		r >= 0xffe0 && r <= 0xffe6,   // fullwidth signs
+		r >= 0x1f300 && r <= 0x1f64f, // miscellaneous symbols and pictographs, emoticons
+		r >= 0x1f900 && r <= 0x1f9ff, // supplemental symbols and pictographs
+		r >= 0x20000 && r <= 0x3fffd: // CJK unified ideographs extensions B and later
+		return 2
+	default:
+		return 1
+	}
+}
+
+func (e *editor) insert(runes ...rune) {
+	line := make([]rune, 0, len(e.line)+len(runes))
+	line = append(line, e.line[:e.pos]...)
+	line = append(line, runes...)
+	line = append(line, e.line[e.pos:]...)
+	e.line = line
+	e.pos += len(runes)
+}
+
+// deleteRune delete the rune under the cursor
+func (e *editor) deleteRune() {
+	if e.pos < len(e.line) {
+		e.line = append(e.line[:e.pos], e.line[e.pos+1:]...)
+	}
+}
+
+// deleteWord delete the word before the cursor, and the blanks after it
+func (e *editor) deleteWord() {
+	start := e.pos
+	for start > 0 && unicode.IsSpace(e.line[start-1]) {
+		start--
+	}
+	for start > 0 && !unicode.IsSpace(e.line[start-1]) {
+		start--
+	}
+	e.line = append(e.line[:start], e.line[e.pos:]...)
+	e.pos = start
+}
+
+func (e *editor) moveLeft() {
+	if e.pos > 0 {
+		e.pos--
+	}
+}
+
+func (e *editor) moveRight() {
+	if e.pos < len(e.line) {
+		e.pos++
+	}
+}
+
+// escape handle an escape sequence, like the arrow keys, whose ESC has been read
+func (e *editor) escape() {
+	r, _, err := e.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return
+	}
+
+	var params []rune
+	for {
+		r, _, err = e.in.ReadRune()
+		if err != nil {
+			return
+		}
+		if r >= 0x40 && r <= 0x7e { // the final byte of the sequence
+			break
+		}
+		params = append(params, r)
+	}
+
+	switch r {
+	case 'A':
+		e.showHistory(e.histPos - 1)
+	case 'B':
+		e.showHistory(e.histPos + 1)
+	case 'C':
+		e.moveRight()
+	case 'D':
+		e.moveLeft()
+	case 'H':
+		e.pos = 0
+	case 'F':
+		e.pos = len(e.line)
+	case '~':
+		switch string(params) {
+		case "1", "7":
+			e.pos = 0
+		case "4", "8":
+			e.pos = len(e.line)
+		case "3":
+			e.deleteRune()
+		}
+	}
+}
+
+// showHistory replace the line with the history entry at index i, or with the typed line after the last entry
+func (e *editor) showHistory(i int) {
+	if i < 0 || i > len(e.history) {
+		return
+	}
+	if e.histPos == len(e.history) {
+		e.typed = e.line
+	}
+
+	e.histPos = i
+	if i == len(e.history) {
+		e.line = e.typed
+	} else {
+		e.line = []rune(e.history[i])
+	}
+	e.pos = len(e.line)
+}
+
+// search find a history entry containing the typed query, newer entries first, like Ctrl-R of bash.
+// Ctrl-R again finds an older entry, Ctrl-G cancels. search report whether Enter was pressed to accept the entry.
+func (e *editor) search() bool {
+	var query []rune
+	found := len(e.history)
+	match := ""
+
+	for {
+		fmt.Fprintf(e.out, "\r(reverse-i-search)`%s': %s\x1b[K", string(query), match)
+
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return false
+		}
+
+		switch {
+		case r == ctrl('R'):
+			if i := e.findHistory(string(query), found-1); i >= 0 {
+				found, match = i, e.history[i]
+			}
+		case r == ctrl('H') || r == 127:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				found, match = e.searchFromEnd(string(query))
+			}
+		case r == ctrl('G') || r == ctrl('C'):
+			return false
+		case unicode.IsPrint(r):
+			query = append(query, r)
+			if i := e.findHistory(string(query), found); i >= 0 {
+				found, match = i, e.history[i]
+			}
+		default:
+			// Any other key accepts the entry, and edits it
+			if match != "" {
+				e.line = []rune(match)
+				e.pos = len(e.line)
+				e.histPos = len(e.history)
+			}
+			if r == '\r' || r == '\n' {
+				return true
+			}
+			if r == 0x1b {
+				e.escape()
+			}
+			return false
+		}
+	}
+}
+
+// findHistory return the index of the newest entry at or before from which contains query, or -1
+func (e *editor) findHistory(query string, from int) int {
+	if from >= len(e.history) {
+		from = len(e.history) - 1
+	}
+	for i := from; i >= 0; i-- {
+		if strings.Contains(e.history[i], query) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (e *editor) searchFromEnd(query string) (int, string) {
+	if query == "" {
+		return len(e.history), ""
+	}
+	if i := e.findHistory(query, len(e.history)-1); i >= 0 {
+		return i, e.history[i]
+	}
+	return len(e.history), ""
+}
+
+// completeWord complete the identifier before the cursor with the longest prefix its candidates share.
+// When it cannot be extended, a second tab lists the candidates.
+func (e *editor) completeWord() {
+	start := e.pos
+	for start > 0 && isIdentRune(e.line[start-1]) {
+		start--
+	}
+	prefix := string(e.line[start:e.pos])
+	if prefix == "" || e.complete == nil {
+		return
+	}
+
+	candidates := matchingCandidates(e.complete(prefix), prefix)
+	if len(candidates) == 0 {
+		io.WriteString(e.out, "\a")
+		return
+	}
+
+	common := commonPrefix(candidates)
+	if len(common) > len(prefix) {
+		e.insert([]rune(common[len(prefix):])...)
+		return
+	}
+
+	if len(candidates) > 1 && e.lastTab {
+		io.WriteString(e.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+	}
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// matchingCandidates return the candidates which start with prefix, sorted and without duplicates
+func matchingCandidates(candidates []string, prefix string) []string {
+	seen := map[string]bool{}
+	var matching []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) && !seen[c] {
+			seen[c] = true
+			matching = append(matching, c)
+		}
+	}
+	sort.Strings(matching)
+	return matching
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// loadHistory read the history from path, which is then appended with the new entries
+func (e *editor) loadHistory(path string) {
+	e.historyFile = path
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			e.history = append(e.history, line)
+		}
+	}
+
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+		ioutil.WriteFile(path, []byte(strings.Join(e.history, "\n")+"\n"), 0600)
+	}
+}
+
+// addHistory append line to the history, unless it is blank or repeats the last entry
+func (e *editor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[1:]
+	}
+
+	if e.historyFile == "" {
+		return
+	}
+	f, err := os.OpenFile(e.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, line)
+}
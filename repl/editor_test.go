@@ -0,0 +1,115 @@
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestEditor(input string, history ...string) *editor {
+	raw := func() (func(), error) { return func() {}, nil }
+	complete := func(prefix string) []string { return []string{"let", "len", "last", "length", "puts"} }
+
+	e := newEditor(bufio.NewReader(strings.NewReader(input)), ioutil.Discard, raw, complete)
+	e.history = history
+	return e
+}
+
+func TestEditorReadLine(t *testing.T) {
+	tests := []struct {
+		input    string
+		history  []string
+		expected string
+	}{
+		{"abc\r", nil, "abc"},
+		{"abc\x1b[D\x1b[DX\r", nil, "aXbc"},
+		{"abc\x01X\x05Y\r", nil, "XabcY"},
+		{"abc\x7f\x7f\r", nil, "a"},
+		{"abc\x1b[D\x1b[3~\r", nil, "ab"},
+		{"let x = 1\x17\x17\x17y\r", nil, "let y"},
+		{"abc\x1b[D\x0b\r", nil, "ab"},
+		{"abc\x1b[D\x15\r", nil, "c"},
+		{"\x1b[A\r", []string{"first", "second"}, "second"},
+		{"\x1b[A\x1b[A\r", []string{"first", "second"}, "first"},
+		{"typed\x1b[A\x1b[B\r", []string{"first"}, "typed"},
+		{"\x12fir\r", []string{"first", "second"}, "first"},
+		{"\x12s\x12\r", []string{"is one", "second"}, "is one"},
+		{"\x12sec\x1b[D!\r", []string{"first", "second"}, "secon!d"},
+		{"old\x12zzz\x07\r", []string{"first"}, "old"},
+		{"pu\t(1)\r", nil, "puts(1)"},
+		{"le\t\r", nil, "le"},
+		{"len\tg\r", nil, "leng"},
+		{"la\t\r", nil, "last"},
+		{"abc", nil, "abc"},
+	}
+
+	for _, tt := range tests {
+		e := newTestEditor(tt.input, tt.history...)
+		line, err := e.ReadLine(">> ")
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", tt.input, err)
+			continue
+		}
+		if line != tt.expected {
+			t.Errorf("wrong line for %q. expected=%q, got=%q", tt.input, tt.expected, line)
+		}
+	}
+}
+
+func TestEditorCursorColumn(t *testing.T) {
+	tests := []struct {
+		line     string
+		pos      int
+		expected string
+	}{
+		{"abc", 3, "\x1b[K"},
+		{"abc", 1, "\x1b[K\x1b[2D"},
+		{"日本語", 1, "\x1b[K\x1b[4D"},
+		{"a한글b", 1, "\x1b[K\x1b[5D"},
+		{"e\u0301x", 0, "\x1b[K\x1b[2D"},
+		{"(🐒)", 1, "\x1b[K\x1b[3D"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		e := newTestEditor("")
+		e.out = &out
+		e.prompt, e.line, e.pos = ">> ", []rune(tt.line), tt.pos
+
+		e.refresh()
+		if !strings.HasSuffix(out.String(), tt.line+tt.expected) {
+			t.Errorf("wrong cursor for %q at %d. expected suffix=%q, got=%q", tt.line, tt.pos, tt.expected, out.String())
+		}
+	}
+}
+
+func TestEditorKeysEndingInput(t *testing.T) {
+	if _, err := newTestEditor("abc\x03").ReadLine(">> "); err != ErrInterrupted {
+		t.Errorf("Ctrl-C does not interrupt. got=%v", err)
+	}
+	if _, err := newTestEditor("\x04").ReadLine(">> "); err != io.EOF {
+		t.Errorf("Ctrl-D on an empty line is not EOF. got=%v", err)
+	}
+}
+
+func TestEditorHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	e := newTestEditor("one\rone\r\rtwo\r")
+	e.loadHistory(path)
+	for i := 0; i < 4; i++ {
+		if _, err := e.ReadLine(">> "); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	loaded := newTestEditor("")
+	loaded.loadHistory(path)
+	if strings.Join(loaded.history, ",") != "one,two" {
+		t.Errorf("wrong history. expected=one,two, got=%q", loaded.history)
+	}
+}
@@ -0,0 +1,112 @@
+package repl
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/NAKKA-K/learn-interpreter-in-go/lexer"
+	"github.com/NAKKA-K/learn-interpreter-in-go/object"
+	"github.com/NAKKA-K/learn-interpreter-in-go/token"
+)
+
+// ANSI escape sequences of the colours of the REPL
+const (
+	colorReset   = "\x1b[0m"
+	colorRed     = "\x1b[31m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorBlue    = "\x1b[34m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+	colorGrey    = "\x1b[90m"
+)
+
+var ansiSequence = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// paint wrap s in color
+func paint(s, color string) string {
+	if color == "" || s == "" {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// visibleLength return the number of runes of s without its colours
+func visibleLength(s string) int {
+	return len([]rune(ansiSequence.ReplaceAllString(s, "")))
+}
+
+// tokenColor return the colour of a token of type t, or "" when it is not coloured
+func tokenColor(t token.TokenType) string {
+	switch t {
+	case token.INT, token.FLOAT:
+		return colorCyan
+	case token.STRING:
+		return colorGreen
+	case token.TRUE, token.FALSE:
+		return colorYellow // coloured as values, although they are keywords
+	case token.ILLEGAL:
+		return colorRed
+	}
+	if keywordTypes[t] {
+		return colorBlue
+	}
+	return ""
+}
+
+var keywordTypes = func() map[token.TokenType]bool {
+	types := map[token.TokenType]bool{}
+	for _, keyword := range token.Keywords() {
+		types[token.LookupIdent(keyword)] = true
+	}
+	return types
+}()
+
+// highlight colour the tokens and comments of source, which is otherwise kept as it is
+func highlight(source string) string {
+	var out strings.Builder
+	l := lexer.New(source)
+
+	last := 0 // offset of the first byte not written yet
+	write := func(start, end int, color string) {
+		if start < last || end > len(source) || start > end {
+			return
+		}
+		out.WriteString(source[last:start])
+		out.WriteString(paint(source[start:end], color))
+		last = end
+	}
+
+	for {
+		tok := l.NextToken()
+		for _, comment := range tok.Comments {
+			write(comment.Pos.Offset, comment.End.Offset, colorGrey)
+		}
+		if tok.Type == token.EOF {
+			break
+		}
+		write(tok.Pos.Offset, tok.End.Offset, tokenColor(tok.Type))
+	}
+	out.WriteString(source[last:])
+
+	return out.String()
+}
+
+// objectColor return the colour of a value of type t
+func objectColor(t object.ObjectType) string {
+	switch t {
+	case object.INTEGER_OBJ, object.FLOAT_OBJ:
+		return colorCyan
+	case object.STRING_OBJ:
+		return colorGreen
+	case object.BOOLEAN_OBJ:
+		return colorYellow
+	case object.NULL_OBJ:
+		return colorGrey
+	case object.FUNCTION_OBJ, object.BUILTIN_OBJ, object.MACRO_OBJ:
+		return colorMagenta
+	case object.ERROR_OBJ:
+		return colorRed
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+package repl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/NAKKA-K/learn-interpreter-in-go/object"
+)
+
+const (
+	// maxItems is the number of elements of an array or pairs of a hash which are printed
+	maxItems = 100
+
+	// maxInlineWidth is the width up to which an array or a hash is printed on one line
+	maxInlineWidth = 72
+)
+
+// prettyPrinter format values for the REPL: strings are quoted, and long arrays and hashes
+// are printed one element per line, indented
+type prettyPrinter struct {
+	color bool // colour the values by their type
+}
+
+// format return obj formatted at the indentation level indent
+func (p *prettyPrinter) format(obj object.Object, indent int) string {
+	return p.formatNested(obj, indent, map[object.Object]bool{})
+}
+
+// formatNested format obj inside the arrays and hashes of enclosing, which are shown as "[...]" and "{...}"
+// when obj refers back to them
+func (p *prettyPrinter) formatNested(obj object.Object, indent int, enclosing map[object.Object]bool) string {
+	switch obj := obj.(type) {
+	case *object.String:
+		return p.paint(strconv.Quote(obj.Value), object.STRING_OBJ)
+
+	case *object.Array:
+		if enclosing[obj] {
+			return "[...]"
+		}
+		enclosing[obj] = true
+		defer delete(enclosing, obj)
+
+		var items []string
+		for i, element := range obj.Elements {
+			if i == maxItems {
+				items = append(items, p.more(len(obj.Elements)-maxItems))
+				break
+			}
+			items = append(items, p.formatNested(element, indent+1, enclosing))
+		}
+		return p.collection("[", "]", items, indent)
+
+	case *object.Hash:
+		if enclosing[obj] {
+			return "{...}"
+		}
+		enclosing[obj] = true
+		defer delete(enclosing, obj)
+
+		var items []string
+		for i, key := range obj.Keys() {
+			if i == maxItems {
+				items = append(items, p.more(len(obj.Pairs)-maxItems))
+				break
+			}
+			pair := obj.Pairs[key.(object.Hashable).HashKey()]
+			items = append(items, p.formatNested(pair.Key, indent+1, enclosing)+": "+p.formatNested(pair.Value, indent+1, enclosing))
+		}
+		return p.collection("{", "}", items, indent)
+	}
+
+	return p.paint(obj.Inspect(), obj.Type())
+}
+
+// collection return the items between open and close, on one line when they fit
+func (p *prettyPrinter) collection(open, close string, items []string, indent int) string {
+	inline := open + strings.Join(items, ", ") + close
+	if !strings.Contains(inline, "\n") && indent*2+visibleLength(inline) <= maxInlineWidth {
+		return inline
+	}
+
+	var out strings.Builder
+	out.WriteString(open + "\n")
+	for _, item := range items {
+		out.WriteString(strings.Repeat("  ", indent+1) + item + ",\n")
+	}
+	out.WriteString(strings.Repeat("  ", indent) + close)
+	return out.String()
+}
+
+func (p *prettyPrinter) more(n int) string {
+	s := fmt.Sprintf("... %d more", n)
+	if !p.color {
+		return s
+	}
+	return paint(s, colorGrey)
+}
+
+func (p *prettyPrinter) paint(s string, t object.ObjectType) string {
+	if !p.color {
+		return s
+	}
+	return paint(s, objectColor(t))
+}
@@ -0,0 +1,83 @@
+package repl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NAKKA-K/learn-interpreter-in-go/object"
+)
+
+func TestPrettyPrinter(t *testing.T) {
+	long := &object.Array{}
+	for i := 0; i < 30; i++ {
+		long.Elements = append(long.Elements, &object.Integer{Value: int64(i)})
+	}
+	huge := &object.Array{}
+	for i := 0; i < maxItems+5; i++ {
+		huge.Elements = append(huge.Elements, &object.Integer{Value: 1})
+	}
+
+	tests := []struct {
+		input    object.Object
+		expected string
+	}{
+		{&object.Integer{Value: 5}, "5"},
+		{&object.String{Value: "a\"b\n"}, `"a\"b\n"`},
+		{&object.Array{Elements: []object.Object{&object.String{Value: "x"}, object.TRUE}}, `["x", true]`},
+		{&object.Array{Elements: []object.Object{long}}, "[\n  [\n    0,\n    1,\n"},
+	}
+
+	for _, tt := range tests {
+		got := (&prettyPrinter{}).format(tt.input, 0)
+		if !strings.HasPrefix(got, tt.expected) {
+			t.Errorf("wrong format. expected prefix=%q, got=%q", tt.expected, got)
+		}
+	}
+
+	got := (&prettyPrinter{}).format(long, 0)
+	lines := strings.Split(got, "\n")
+	if len(lines) != 32 || lines[1] != "  0," || lines[31] != "]" {
+		t.Errorf("long array is not printed one element per line. got=%q", got)
+	}
+
+	got = (&prettyPrinter{}).format(huge, 0)
+	if !strings.Contains(got, "  ... 5 more,\n") || strings.Count(got, "\n") != maxItems+2 {
+		t.Errorf("huge array is not truncated. got=%q", got)
+	}
+
+	got = (&prettyPrinter{color: true}).format(&object.String{Value: "s"}, 0)
+	if got != colorGreen+`"s"`+colorReset {
+		t.Errorf("string is not coloured. got=%q", got)
+	}
+}
+
+func TestPrettyPrinterCycles(t *testing.T) {
+	array := &object.Array{Elements: []object.Object{&object.Integer{Value: 1}}}
+	array.Elements = append(array.Elements, array)
+
+	if got := (&prettyPrinter{}).format(array, 0); got != "[1, [...]]" {
+		t.Errorf("wrong format of a cyclic array. got=%q", got)
+	}
+
+	key := &object.String{Value: "self"}
+	hash := &object.Hash{Pairs: map[object.HashKey]object.HashPair{}}
+	hash.Pairs[key.HashKey()] = object.HashPair{Key: key, Value: hash}
+
+	if got := (&prettyPrinter{}).format(hash, 0); got != `{"self": {...}}` {
+		t.Errorf("wrong format of a cyclic hash. got=%q", got)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	source := `let x = "s" + 1; // done`
+	expected := paint("let", colorBlue) + ` x = ` + paint(`"s"`, colorGreen) + ` + ` + paint("1", colorCyan) +
+		`; ` + paint("// done", colorGrey)
+
+	got := highlight(source)
+	if got != expected {
+		t.Errorf("wrong highlight.\nwant=%q\ngot= %q", expected, got)
+	}
+	if visibleLength(got) != len(source) {
+		t.Errorf("highlight changes the text. got=%q", got)
+	}
+}
@@ -2,56 +2,294 @@ package repl
 
 import (
 	"bufio"
-	"fmt"
+	"context"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/NAKKA-K/learn-interpreter-in-go/ast"
+	"github.com/NAKKA-K/learn-interpreter-in-go/compiler"
 	"github.com/NAKKA-K/learn-interpreter-in-go/evaluator"
 	"github.com/NAKKA-K/learn-interpreter-in-go/lexer"
 	"github.com/NAKKA-K/learn-interpreter-in-go/object"
 	"github.com/NAKKA-K/learn-interpreter-in-go/parser"
+	"github.com/NAKKA-K/learn-interpreter-in-go/token"
+	"github.com/NAKKA-K/learn-interpreter-in-go/vm"
 )
 
 // PROMPT is the waiting read icon of CUI interface
 const PROMPT = ">> "
 
-// Start prompt
-func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
-	macroEnv := object.NewEnvironment()
+// CONTINUATION_PROMPT is shown while the input is an incomplete statement
+const CONTINUATION_PROMPT = ".. "
+
+// Engine is the way the REPL executes programs
+type Engine int
+
+const (
+	EngineEval Engine = iota // the tree-walking evaluator
+	EngineVM                 // the bytecode compiler and virtual machine
+)
+
+// Start prompt. When in and out are a terminal, lines are read with a line editor, which keeps its history
+// in ~/.monkey_history. When out is a terminal, the input and the results are coloured.
+// maxDepth is the number of nested function calls allowed, the default of the engine when 0.
+func Start(in io.Reader, out io.Writer, engine Engine, maxDepth int) {
+	// The reading builtins share the reader with the REPL, so that neither loses the input buffered by the other
+	reader := bufio.NewReader(in)
+	s := newSession(engine, out, &object.IO{Stdin: reader, Stdout: out, Stderr: out})
+	s.maxDepth = maxDepth
+	s.printer.color = isTerminalWriter(out)
+
+	var lines lineReader = &plainReader{in: reader, out: out}
+	if terminal, ok := in.(*os.File); ok && isTerminal(terminal.Fd()) && isTerminalWriter(out) {
+		raw := func() (func(), error) { return makeRaw(terminal.Fd()) }
+		editor := newEditor(reader, out, raw, s.completions)
+		editor.highlight = highlight
+		if home, err := os.UserHomeDir(); err == nil {
+			editor.loadHistory(filepath.Join(home, ".monkey_history"))
+		}
+		lines = editor
+	}
+
+	var pending []string // the lines of an incomplete statement
+	blanks := 0          // consecutive blank lines in pending
 
 	for {
-		fmt.Printf(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		prompt := PROMPT
+		if len(pending) > 0 {
+			prompt = CONTINUATION_PROMPT
+		}
+
+		line, err := lines.ReadLine(prompt)
+		if err == ErrInterrupted {
+			// Ctrl-C abandons an incomplete statement
+			pending, blanks = nil, 0
+			continue
+		}
+		if err != nil {
 			return
 		}
 
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
-		program := p.ParseProgram()
+		if len(pending) == 0 && strings.HasPrefix(line, ":") {
+			if quit := s.command(line); quit {
+				return
+			}
+			continue
+		}
 
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+		if len(pending) > 0 && strings.TrimSpace(line) == "" {
+			// Two blank lines abandon an incomplete statement
+			if blanks++; blanks == 2 {
+				io.WriteString(out, "input discarded\n")
+				pending, blanks = nil, 0
+				continue
+			}
+		} else {
+			blanks = 0
+		}
+
+		pending = append(pending, line)
+		source := strings.Join(pending, "\n")
+		if isIncomplete(source) {
 			continue
 		}
+		pending, blanks = nil, 0
+
+		s.run("", source)
+	}
+}
+
+func isTerminalWriter(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && isTerminal(f.Fd())
+}
+
+// session is the state of a REPL, which is kept between its inputs
+type session struct {
+	engine   Engine
+	out      io.Writer
+	stdio    *object.IO
+	macroEnv *object.Environment
+	printer  prettyPrinter
+	maxDepth int // 0 means the default of the engine
+
+	// EngineEval
+	env     *object.Environment
+	modules *evaluator.Modules
+
+	// EngineVM
+	constants   []object.Object
+	globals     []object.Object
+	symbolTable *compiler.SymbolTable
+}
+
+func newSession(engine Engine, out io.Writer, stdio *object.IO) *session {
+	s := &session{engine: engine, out: out, stdio: stdio}
+	s.reset()
+	return s
+}
+
+// reset forget every binding, macro and imported module of the session
+func (s *session) reset() {
+	s.macroEnv = object.NewEnvironment()
+	s.env = object.NewEnvironment()
+	s.modules = evaluator.NewModules(evaluator.ModulePath()...)
+
+	s.constants = []object.Object{}
+	s.globals = make([]object.Object, vm.GlobalsSize)
+	s.symbolTable = compiler.NewSymbolTable()
+	for i, v := range object.Builtins {
+		s.symbolTable.DefineBuiltin(i, v.Name)
+	}
+}
+
+// run evaluate source, read from filename when it is not empty, and print its value or its errors
+func (s *session) run(filename, source string) {
+	evaluated, ok := s.eval(filename, source)
+	if ok && evaluated != nil {
+		io.WriteString(s.out, s.printer.format(evaluated, 0))
+		io.WriteString(s.out, "\n")
+	}
+}
+
+// eval evaluate source and return its value. Errors are printed, and reported by false.
+func (s *session) eval(filename, source string) (object.Object, bool) {
+	program, ok := s.parse(filename, source)
+	if !ok {
+		return nil, false
+	}
+
+	evaluator.DefineMacros(program, s.macroEnv)
+	expanded := evaluator.ExpandMacros(program, s.macroEnv)
+
+	evaluated, err := s.execute(expanded.(*ast.Program))
+	if err != nil {
+		if errObj, ok := err.(*object.Error); ok {
+			io.WriteString(s.out, errObj.Traceback())
+		} else {
+			io.WriteString(s.out, err.Error())
+		}
+		io.WriteString(s.out, "\n")
+		return nil, false
+	}
+	return evaluated, true
+}
+
+// parse return the program of source, or print its parse errors
+func (s *session) parse(filename, source string) (*ast.Program, bool) {
+	p := parser.New(lexer.NewFile(filename, source))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		printParserErrors(s.out, source, p.ParseErrors())
+		return nil, false
+	}
+	return program, true
+}
+
+// execute run program with the engine of the session, keeping the globals between runs
+func (s *session) execute(program *ast.Program) (object.Object, error) {
+	if s.engine == EngineVM {
+		comp := compiler.NewWithState(s.symbolTable, s.constants)
+		if err := comp.Compile(program); err != nil {
+			return nil, err
+		}
+
+		bytecode := comp.Bytecode()
+		s.constants = bytecode.Constants
+
+		machine := vm.NewWithGlobalsStore(bytecode, s.globals)
+		machine.SetIO(s.stdio)
+		if s.maxDepth > 0 {
+			machine.SetMaxDepth(s.maxDepth)
+		}
+		if err := machine.Run(); err != nil {
+			return nil, err
+		}
+		return machine.Result(), nil
+	}
+
+	evaluated := evaluator.EvalContext(context.Background(), program, s.env, evaluator.Options{IO: s.stdio, MaxDepth: s.maxDepth, Modules: s.modules})
+	if errObj, ok := evaluated.(*object.Error); ok {
+		return nil, errObj
+	}
+	return evaluated, nil
+}
+
+// completions return the names which can be completed in the session:
+// the keywords, the builtins, the globals and the macros
+func (s *session) completions(prefix string) []string {
+	names := token.Keywords()
+	for _, builtin := range object.Builtins {
+		names = append(names, builtin.Name)
+	}
+	for name := range s.bindings() {
+		names = append(names, name)
+	}
+	names = append(names, s.macroEnv.Names()...)
+	return names
+}
+
+// bindings return the globals of the session by name
+func (s *session) bindings() map[string]object.Object {
+	bindings := map[string]object.Object{}
+
+	if s.engine == EngineVM {
+		for i, name := range s.symbolTable.Names() {
+			// hidden symbols of the compiler are named like "<iterator>"
+			if s.globals[i] != nil && !strings.HasPrefix(name, "<") {
+				bindings[name] = s.globals[i]
+			}
+		}
+		return bindings
+	}
 
-		evaluator.DefineMacros(program, macroEnv)
-		expanded := evaluator.ExpandMacros(program, macroEnv)
+	for _, name := range s.env.Names() {
+		bindings[name], _ = s.env.Get(name)
+	}
+	return bindings
+}
+
+// continuationTokens are the tokens after which a statement cannot end
+var continuationTokens = map[token.TokenType]bool{
+	token.ASSIGN: true, token.PLUS_ASSIGN: true, token.MINUS_ASSIGN: true, token.ASTERISK_ASSIGN: true, token.SLASH_ASSIGN: true,
+	token.PLUS: true, token.MINUS: true, token.ASTERISK: true, token.SLASH: true, token.PERCENT: true, token.BANG: true,
+	token.EQ: true, token.NOT_EQ: true, token.LT: true, token.GT: true, token.LT_EQ: true, token.GT_EQ: true,
+	token.AND: true, token.OR: true, token.COMMA: true, token.COLON: true, token.DOT: true,
+}
+
+// isIncomplete report whether more lines are needed to complete source: it has unclosed brackets,
+// an unterminated string or block comment, or it ends with an operator
+func isIncomplete(source string) bool {
+	l := lexer.New(source)
 
-		evaluated := evaluator.Eval(expanded, env)
-		if evaluated != nil {
-			io.WriteString(out, evaluated.Inspect())
-			io.WriteString(out, "\n")
+	depth := 0
+	var last token.Token
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LPAREN, token.LBRACE, token.LBRACKET:
+			depth++
+		case token.RPAREN, token.RBRACE, token.RBRACKET:
+			depth--
 		}
+		last = tok
 	}
+
+	for _, err := range l.Errors() {
+		if err.Unterminated {
+			return true
+		}
+	}
+
+	return depth > 0 || continuationTokens[last.Type]
 }
 
-func printParserErrors(out io.Writer, errors []string) {
+func printParserErrors(out io.Writer, source string, errors []*parser.ParseError) {
 	io.WriteString(out, "Woops! We ran into some monkey business here!\n")
 	io.WriteString(out, " parser errors:\n")
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+	for _, err := range errors {
+		io.WriteString(out, err.Render(source))
 	}
 }
@@ -0,0 +1,112 @@
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestIsIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"let x = 1;", false},
+		{"let add = fn(a, b) {", true},
+		{"let add = fn(a, b) {\n a + b\n}", false},
+		{"[1, 2,", true},
+		{"puts(1,\n2", true},
+		{`"unterminated`, true},
+		{"/* comment", true},
+		{`let x = "\u{zz"; x`, false},
+		{`"\q`, true},
+		{"1 +", true},
+		{"x &&", true},
+		{"let x =", true},
+		{"x }", false},
+		{"// comment", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := isIncomplete(tt.input); got != tt.expected {
+			t.Errorf("isIncomplete(%q) wrong. expected=%t, got=%t", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestMultiLineInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let add = fn(a, b) {\n  a + b\n};\nadd(1,\n 2)\n",
+			">> .. .. >> .. 3\n>> ",
+		},
+		{
+			"let x = [1,\n\n\n1 + 1\n",
+			">> .. .. input discarded\n>> 2\n>> ",
+		},
+		{
+			"\"a\n b\"\n",
+			">> .. \"a\\n b\"\n>> ",
+		},
+	}
+
+	for _, engine := range []Engine{EngineEval, EngineVM} {
+		for _, tt := range tests {
+			var out bytes.Buffer
+			Start(strings.NewReader(tt.input), &out, engine, 0)
+
+			if out.String() != tt.expected {
+				t.Errorf("wrong output for %q with engine %d.\nwant=%q\ngot= %q", tt.input, engine, tt.expected, out.String())
+			}
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let b = 2; let a = 1;\n:env\n", []string{"a = 1\nb = 2\n"}},
+		{"let unless = macro(c, x) { quote(if (!(unquote(c))) { unquote(x) }) };\n:macros\n", []string{"unless(c, x)\n"}},
+		{":ast 1 + 2\n", []string{"Program 1:1\n  Statements: ExpressionStatement 1:1\n    Expression: InfixExpression Operator=\"+\" 1:1\n      Left: IntegerLiteral Value=1 1:1\n      Right: IntegerLiteral Value=2 1:5\n"}},
+		{":tokens x = 1\n", []string{"1:1    IDENT      \"x\"\n1:3    =          \"=\"\n1:5    INT        \"1\"\n"}},
+		{":type [1]\n", []string{"ARRAY\n"}},
+		{":time 1 + 1\n", []string{"2\ntime: "}},
+		{"let a = 1;\n:reset\n:env\na\n", []string{"session reset\n>> >> ERROR: 1:1: identifier not found: a"}},
+		{":quit\n1\n", []string{">> "}},
+		{":load\n:what\n", []string{"usage: :load file\n", "unknown command :what"}},
+		{":help\n", []string{":load file", ":quit"}},
+	}
+
+	for _, engine := range []Engine{EngineEval, EngineVM} {
+		for _, tt := range tests {
+			var out bytes.Buffer
+			Start(strings.NewReader(tt.input), &out, engine, 0)
+
+			for _, expected := range tt.expected {
+				if !strings.Contains(out.String(), expected) {
+					t.Errorf("wrong output for %q with engine %d. expected to contain %q, got=%q", tt.input, engine, expected, out.String())
+				}
+			}
+		}
+	}
+}
+
+func TestHelpOfVMEngine(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader(":help\n"), &out, EngineVM, 0)
+	if !strings.Contains(out.String(), "import is not supported by the vm engine") {
+		t.Errorf(":help does not tell that import is not supported. got=%q", out.String())
+	}
+
+	out.Reset()
+	Start(strings.NewReader(":help\n"), &out, EngineEval, 0)
+	if strings.Contains(out.String(), "import is not supported") {
+		t.Errorf(":help of the evaluator tells that import is not supported. got=%q", out.String())
+	}
+}
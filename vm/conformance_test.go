@@ -0,0 +1,297 @@
+package vm
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/NAKKA-K/learn-interpreter-in-go/ast"
+	"github.com/NAKKA-K/learn-interpreter-in-go/compiler"
+	"github.com/NAKKA-K/learn-interpreter-in-go/evaluator"
+	"github.com/NAKKA-K/learn-interpreter-in-go/lexer"
+	"github.com/NAKKA-K/learn-interpreter-in-go/object"
+	"github.com/NAKKA-K/learn-interpreter-in-go/parser"
+)
+
+// conformanceTests are run by both the evaluator and the VM, which must agree on the result.
+// Errors are compared with their position and call stack.
+var conformanceTests = []string{
+	// integers and floats
+	"5",
+	"-10",
+	"5 + 5 + 5 + 5 - 10",
+	"(5 + 10 * 2 + 15 / 3) * 2 + -10",
+	"7 % 3",
+	"-7 % 3",
+	"2 + 10 % 4 * 3",
+	"3.5",
+	"1e-3",
+	"0.1 + 0.2 * 2",
+	"7 / 2.0",
+	"(1 + 2) / 4.0",
+	"5.5 % 2",
+	"1 == 1.0",
+	"0.1 + 0.2 == 0.3",
+	"2.5 > 3",
+	"10 / 0",
+	"5 % 0",
+	"1.5 + true",
+
+	// booleans and logical operators
+	"true",
+	"1 < 2",
+	"1 <= 1",
+	"2 >= 3",
+	"true == true",
+	"(1 < 2) == false",
+	"true != false",
+	"1 == true",
+	"[1] == [1]",
+	"!5",
+	"!!true",
+	"!0",
+	`5 || 10`,
+	`false || "default"`,
+	`let x = if (false) { 1 }; x || "fallback"`,
+	`0 && 10`,
+	`false && undefinedName`,
+	`true || undefinedName`,
+	`true && undefinedName`,
+	`let n = 0; let inc = fn() { n += 1; true }; false && inc(); true || inc(); n`,
+
+	// conditionals and return
+	"if (true) { 10 }",
+	"if (false) { 10 }",
+	"if (1 > 2) { 10 } else { 20 }",
+	"if (1) { 10 }",
+	"return 10; 9;",
+	"9; return 2 * 5; 9;",
+	"if (10 > 1) { if (10 > 1) { return 10; } return 1; }",
+	"let f = fn(x) { if (x) { return 1; } 2 }; [f(true), f(false)]",
+
+	// errors
+	"5 + true; 5;",
+	"-true",
+	"5; true + false; 5;",
+	"if (10 > 1) { if (10 > 1) { return true + false; } }",
+	"foobar",
+	`"Hello" - "World"`,
+	`"a" == "a"`,
+	`{"name": "Monkey"}[fn(x) { x }];`,
+	"let x = 5;\nx + true;",
+	"let f = fn() {\n  -true\n};\nf();",
+	"len(1)",
+	"5()",
+	"[1, 2][\"a\"]",
+	"let inner = fn(x) {\n  x + true\n};\nlet outer = fn(x) { inner(x) };\nfn() { outer(1) }();",
+	"let f = fn() { g() }; f()",
+	"let f = fn(a, b) { b }; f(1)",
+	"let f = fn(a) { a }; f(1, 2)",
+	"let f = fn(a, b) { b }; let g = fn() { f(1) }; g()",
+	"let f = fn(a, b) { b }; let g = fn() { 1 + f(1, 2, 3) }; g()",
+	"let f = fn(a) { a }; return f();",
+	"let f = fn(n) { 1 + f(n + 1) }; f(0)",
+	"let f = fn(n) { let a = [n, n, n, n, n, n, n, n]; a[0] + f(n + 1) }; f(0)",
+
+	// let, functions and closures
+	"let a = 5; let b = a; let c = a + b + 5; c;",
+	"let a = 5;",
+	"let a = 1; let a = a + 1; a",
+	"let identity = fn(x) { return x; }; identity(5);",
+	"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));",
+	"fn(x){ x; }(5)",
+	"let newAdder = fn(x) { fn(y) { x + y } }; let addTwo = newAdder(2); addTwo(2);",
+	"let f = fn() { let a = 1; let g = fn() { a }; let a = 2; g() }; f()",
+	"let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) }; fib(15)",
+	"let f = fn() { let g = fn(n) { if (n == 0) { 0 } else { g(n - 1) } }; g(5) }; f()",
+	"let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } }; let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } }; isEven(10)",
+	"let a = fn() { let x = 1; fn() { fn() { x } } }; a()()()",
+	"let f = fn() { let g = fn() { h() }; let h = fn() { 1 }; g() }; f()",
+	"let f = fn() { let g = fn() { n * 2 }; let n = 21; g() }; f()",
+	"let x = 1; let f = fn() { let x = x + 1; x }; f()",
+	"let f = fn() { let g = fn() { n }; let r = g(); let n = 1; r }; f()",
+	"let f = fn() { let t = 0; for (i in [1, 2]) { let g = fn() { k }; let k = i; let t = t + g(); }; t }; f()",
+	"let f = fn(x) { let g = fn() { x += 1 }; g(); g(); x }; f(1)",
+
+	// tail calls
+	"let countdown = fn(n) { if (n == 0) { 0 } else { countdown(n - 1) } }; countdown(1000000)",
+	"let sum = fn(n, acc) { if (n == 0) { return acc; } return sum(n - 1, acc + n); }; sum(100000, 0)",
+	"let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } }; let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } }; isEven(100001)",
+	"let f = fn(n) { if (n == 0) { -true } else { f(n - 1) } }; let g = fn() { f(3) }; g()",
+	"let f = fn(n) { if (n == 0) { -true } else { f(n - 1) } }; let g = fn() { f(500) }; g()",
+	"let f = fn(x) { let g = fn() { x }; if (x < 3) { f(x + 1) } else { g() } }; f(0)",
+	"let f = fn(n) { if (n == 0) { return len(1); } return f(n - 1); }; f(2)",
+	"let f = fn(n) { return if (n == 0) { 0 } else { f(n - 1) } }; f(100000)",
+	"let f = fn(n) { return if (n == 0) { -true } else { f(n - 1) } }; let g = fn() { f(3) }; g()",
+	"let f = fn(x) { return if (x) { return 1; } else { 2 } }; [f(true), f(false)]",
+
+	// strings
+	`"Hello" + " " + "World!"`,
+	`"héllo"[1]`,
+	`let s = "日本語"; s[len(s) - 1]`,
+	`"abc"[3]`,
+
+	// builtins
+	`len("héllo")`,
+	`len("one", "two")`,
+	`len([1, 2, 3])`,
+	`first([])`,
+	`last([1, 2, 3])`,
+	`rest([1, 2, 3])`,
+	`push([], 1)`,
+	`push(1, 1)`,
+	`let len = fn(x) { 42 }; len("abc")`,
+	`let map = fn(arr, f) { let iter = fn(arr, accumulated) { if (len(arr) == 0) { accumulated } else { iter(rest(arr), push(accumulated, f(first(arr)))); } }; iter(arr, []) }; map([1, 2, 3], fn(x) { x + 2 });`,
+
+	// arrays and hashes
+	"[1, 2 * 2, 3 + 3]",
+	"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];",
+	"[1, 2, 3][-1]",
+	`let two = "two"; {"one": 10 - 9, two: 1 + 1, "thr" + "ee": 6 / 2, 4: 4, true: 5, false: 6}`,
+	`{"foo": 5}["bar"]`,
+	`{1: 5}[1.0]`,
+	`let user = {"name": "Ann", "address": {"city": "Paris"}}; user.name + " " + user.address.city`,
+	`let user = {"name": "Ann", "greet": fn(who) { "hi " + who }}; user.greet(user.name)`,
+	`let h = {"f": fn() { {"g": fn() { 7 }} }}; h.f().g()`,
+	`let user = {"name": "Ann"};
+user.age`,
+	`{"a": {}}.a.b`,
+	`let x = 5; x.y`,
+	`[1, 2].len`,
+
+	// loops
+	"let i = 0; while (i < 5) { let i = i + 1; }; i",
+	"let i = 0; while (true) { let i = i + 1; if (i == 3) { break; } }; i",
+	"let i = 0; let n = 0; while (i < 5) { let i = i + 1; if (i == 2) { continue; } let n = n + 1; }; n",
+	"let f = fn() { let i = 0; while (true) { let i = i + 1; if (i > 2) { return i * 10; } } }; f()",
+	"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x; } } }; f()",
+	"let f = fn() { for (let i = 0; ; let i = i + 1) { if (i == 7) { return i; } } }; f()",
+	"while (false) { 1 }",
+	"let total = 0; for (let i = 0; i < 10; i += 1) { if (i % 2 == 0) { continue; } total += i; }; total",
+	"let total = 0; for (x in [1, 2, 3]) { total = total + x; }; total",
+	`let f = fn(h) { for (k in h) { return k; } }; f({"b": 1, "a": 2})`,
+	`let out = ""; for (c in "日本") { out += c + "-" }; out`,
+	`for (x in [1]) { x }; x`,
+	`for (x in 5) { x }`,
+	"let fs = []; for (x in [1, 2]) { let fs = push(fs, fn() { x }); }; fs[0]() + fs[1]()",
+	"let f = fn() { let fs = []; for (let i = 0; i < 3; i += 1) { let fs = push(fs, fn() { i }); }; fs[0]() }; f()",
+	"let i = 0; while (i < 100000) { let i = i + 1; }; i",
+	"let i = 0; while (true) { let i = i + 1; let x = if (i == 3) { break } }; i",
+	"let n = 0; for (x in [1, 2, 3]) { n = n + [x, if (x == 2) { continue } else { 0 }][0] }; n",
+	"let i = 0; while (true) { let i = i + 1; [if (i < 3) { i } else { break }] }; i",
+	"let i = 0; while (true) { let i = i + 1; i > 2 && if (true) { break } }; i",
+	`let h = {}; for (x in [1, 2]) { h[x] = {"v": if (x == 1) { continue } else { x }} }; h`,
+
+	// assignment
+	"let x = 1; let y = 2; x = y = 3; x + y",
+	"let a = [1]; a[0] = a; a",
+	`let h = {}; h["self"] = h; h`,
+	"let x = 10; x /= 5; x",
+	"let counter = fn() { let n = 0; fn() { n += 1 } }; let c = counter(); c(); c(); c()",
+	"let n = 0; let f = fn(n) { n = 5; }; f(1); n",
+	"let a = [1, 2, 3]; a[2] *= 10; a",
+	`let h = {"a": 1}; h["a"] += 1; h["a"]`,
+	`let h = {"xs": [1]}; h["xs"][0] = 9; h`,
+	"x = 1",
+	"x += 1",
+	"len = 1",
+	"let a = [1]; a[1] = 2",
+	`let a = [1]; a["x"] = 2`,
+	`let s = "abc"; s[0] = "x"`,
+	`let h = {}; h[fn() {}] = 1`,
+	`let x = 1; x += "a"`,
+	`let h = {"name": "a"}; h.name = "b"; h.name`,
+	`let h = {"name": "a"}; h.name += "b"; h`,
+	`let h = {}; h.count = 1; h.count *= 5; h`,
+	`let user = {"address": {}}; user.address.city = "Paris"; user`,
+	`let h = {"n": 1}; let f = fn() { h.n += 1 }; f(); f()`,
+	`let h = {};
+h.missing += 1`,
+	`let x = 1; x.y = 2`,
+	`let h = {"n": 1}; h.n += true`,
+}
+
+func TestConformance(t *testing.T) {
+	for _, input := range conformanceTests {
+		expected := describe(runEvaluator(t, input))
+		actual := describe(runVM(t, input))
+
+		if actual != expected {
+			t.Errorf("VM disagrees with the evaluator for %q.\nevaluator=%s\nvm=%s", input, expected, actual)
+		}
+	}
+}
+
+func runEvaluator(t *testing.T, input string) object.Object {
+	program := parse(t, input)
+	return evaluator.Eval(program, object.NewEnvironment())
+}
+
+func runVM(t *testing.T, input string) object.Object {
+	program := parse(t, input)
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		t.Fatalf("compiler error for %q: %s", input, err)
+	}
+
+	machine := New(comp.Bytecode())
+	if err := machine.Run(); err != nil {
+		return err.(*object.Error)
+	}
+	return machine.Result()
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+// describe return a representation of obj which the evaluator and the VM can agree on
+func describe(obj object.Object) string {
+	return describeNested(obj, map[object.Object]bool{})
+}
+
+// describeNested describe obj inside the arrays and hashes of enclosing, which are described as
+// "[...]" and "{...}" when obj refers back to them
+func describeNested(obj object.Object, enclosing map[object.Object]bool) string {
+	switch obj := obj.(type) {
+	case nil:
+		return "<nil>"
+	case *object.Error:
+		return obj.Traceback()
+	case *object.Function, *object.Closure:
+		return "FUNCTION"
+	case *object.Array:
+		if enclosing[obj] {
+			return "[...]"
+		}
+		enclosing[obj] = true
+		defer delete(enclosing, obj)
+
+		elements := []string{}
+		for _, e := range obj.Elements {
+			elements = append(elements, describeNested(e, enclosing))
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case *object.Hash:
+		if enclosing[obj] {
+			return "{...}"
+		}
+		enclosing[obj] = true
+		defer delete(enclosing, obj)
+
+		pairs := []string{}
+		for _, pair := range obj.Pairs {
+			pairs = append(pairs, describeNested(pair.Key, enclosing)+": "+describeNested(pair.Value, enclosing))
+		}
+		sort.Strings(pairs)
+		return "{" + strings.Join(pairs, ", ") + "}"
+	default:
+		return string(obj.Type()) + " " + obj.Inspect()
+	}
+}
@@ -0,0 +1,26 @@
+package vm
+
+import (
+	"github.com/NAKKA-K/learn-interpreter-in-go/code"
+	"github.com/NAKKA-K/learn-interpreter-in-go/object"
+)
+
+// Frame is a call of a closure
+type Frame struct {
+	cl          *object.Closure
+	ip          int
+	basePointer int // stack index of the first argument, the locals follow the arguments
+
+	name      string         // name of the function which was called, before any tail call replaced it
+	tailCalls []object.Frame // the tail calls which replaced the called function, outermost first
+}
+
+// NewFrame return a frame which starts executing cl with its locals at basePointer
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{cl: cl, ip: -1, basePointer: basePointer, name: cl.Fn.Name}
+}
+
+// Instructions return the instructions of the executed function
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
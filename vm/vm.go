@@ -0,0 +1,789 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/NAKKA-K/learn-interpreter-in-go/code"
+	"github.com/NAKKA-K/learn-interpreter-in-go/compiler"
+	"github.com/NAKKA-K/learn-interpreter-in-go/object"
+)
+
+const (
+	StackSize   = 2048 // initial size of the stack, which grows as the calls need
+	GlobalsSize = 65536
+	MaxFrames   = 10000 // default maximum number of nested function calls, as evaluator.MaxDepth
+
+	// maxTailFrames is the number of tail calls a frame keeps for the stack of an error
+	maxTailFrames = 100
+)
+
+var (
+	NULL  = object.NULL
+	TRUE  = object.TRUE
+	FALSE = object.FALSE
+)
+
+// VM execute bytecode on a stack
+type VM struct {
+	constants []object.Object
+
+	globals     []object.Object
+	globalNames []string
+
+	stack []object.Object // reallocated by growStack only, which moves the open upvalues pointing into it
+	sp    int             // stack[sp-1] is the top of the stack
+
+	frames      []*Frame
+	framesIndex int
+	maxDepth    int // number of nested function calls allowed
+
+	openUpvalues []openUpvalue
+
+	ctx      context.Context
+	steps    int // number of backward jumps and closure calls so far
+	maxSteps int // 0 means no limit
+
+	stdio *object.IO
+
+	result object.Object
+}
+
+// openUpvalue is an upvalue which still points into the stack
+type openUpvalue struct {
+	slot    int
+	upvalue *object.Upvalue
+}
+
+// New return a VM which executes bytecode with new globals
+func New(bytecode *compiler.Bytecode) *VM {
+	mainFn := &object.CompiledFunction{
+		Instructions: bytecode.Instructions,
+		SourceMap:    bytecode.SourceMap,
+	}
+	mainClosure := &object.Closure{Fn: mainFn}
+	mainFrame := NewFrame(mainClosure, 0)
+
+	frames := []*Frame{mainFrame}
+
+	return &VM{
+		constants: bytecode.Constants,
+
+		globals:     make([]object.Object, GlobalsSize),
+		globalNames: bytecode.GlobalNames,
+
+		stack: make([]object.Object, StackSize),
+		sp:    0,
+
+		frames:      frames,
+		framesIndex: 1,
+		maxDepth:    MaxFrames,
+
+		ctx:   context.Background(),
+		stdio: object.StandardIO(),
+	}
+}
+
+// NewWithGlobalsStore return a VM which executes bytecode with the globals of an earlier execution
+func NewWithGlobalsStore(bytecode *compiler.Bytecode, s []object.Object) *VM {
+	vm := New(bytecode)
+	vm.globals = s
+	return vm
+}
+
+// SetIO make the builtins read and write stdio instead of the standard streams of the process
+func (vm *VM) SetIO(stdio *object.IO) {
+	vm.stdio = stdio
+}
+
+// SetMaxDepth allow n nested function calls instead of MaxFrames, deeper recursion is an error
+func (vm *VM) SetMaxDepth(n int) {
+	vm.maxDepth = n
+}
+
+// Result return the value the program returned, or nil when it ended with a statement which has no value
+func (vm *VM) Result() object.Object {
+	return vm.result
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) {
+	if vm.framesIndex == len(vm.frames) {
+		vm.frames = append(vm.frames, f)
+	} else {
+		vm.frames[vm.framesIndex] = f
+	}
+	vm.framesIndex++
+}
+
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
+
+// RunContext execute the bytecode like Run, but stop with an error when ctx is done,
+// or when more than maxSteps loop iterations and function calls are taken. maxSteps 0 means no limit.
+func (vm *VM) RunContext(ctx context.Context, maxSteps int) error {
+	vm.ctx = ctx
+	vm.maxSteps = maxSteps
+	return vm.Run()
+}
+
+// Run execute the bytecode. A runtime error is returned as an *object.Error.
+func (vm *VM) Run() error {
+	var ip int
+	var ins code.Instructions
+	var op code.Opcode
+
+	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		vm.currentFrame().ip++
+
+		ip = vm.currentFrame().ip
+		ins = vm.currentFrame().Instructions()
+		op = code.Opcode(ins[ip])
+
+		switch op {
+		case code.OpConstant:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			if err := vm.push(vm.constants[constIndex]); err != nil {
+				return err
+			}
+
+		case code.OpPop:
+			vm.pop()
+
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod,
+			code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpGreaterEqual,
+			code.OpLessThan, code.OpLessEqual:
+			right := vm.pop()
+			left := vm.pop()
+
+			result := object.InfixOperation(infixOperators[op], left, right)
+			if err, ok := result.(*object.Error); ok {
+				return vm.raise(err)
+			}
+			if err := vm.push(result); err != nil {
+				return err
+			}
+
+		case code.OpTrue:
+			if err := vm.push(TRUE); err != nil {
+				return err
+			}
+
+		case code.OpFalse:
+			if err := vm.push(FALSE); err != nil {
+				return err
+			}
+
+		case code.OpNull:
+			if err := vm.push(NULL); err != nil {
+				return err
+			}
+
+		case code.OpBang, code.OpMinus:
+			result := object.PrefixOperation(prefixOperators[op], vm.pop())
+			if err, ok := result.(*object.Error); ok {
+				return vm.raise(err)
+			}
+			if err := vm.push(result); err != nil {
+				return err
+			}
+
+		case code.OpJump:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			if pos <= ip {
+				// Jumping back is the next iteration of a loop
+				if err := vm.step(); err != nil {
+					return err
+				}
+			}
+			vm.currentFrame().ip = pos - 1
+
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			if !object.IsTruthy(vm.pop()) {
+				vm.currentFrame().ip = pos - 1
+			}
+
+		case code.OpJumpTruthyOrPop, code.OpJumpNotTruthyOrPop:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			if object.IsTruthy(vm.stack[vm.sp-1]) == (op == code.OpJumpTruthyOrPop) {
+				vm.currentFrame().ip = pos - 1
+			} else {
+				vm.pop()
+			}
+
+		case code.OpSetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			vm.globals[globalIndex] = vm.pop()
+
+		case code.OpGetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			val := vm.globals[globalIndex]
+			if val == nil {
+				return vm.newError("identifier not found: %s", vm.globalNames[globalIndex])
+			}
+			if err := vm.push(val); err != nil {
+				return err
+			}
+
+		case code.OpSetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip++
+
+			vm.stack[vm.currentFrame().basePointer+int(localIndex)] = vm.pop()
+
+		case code.OpGetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip++
+
+			frame := vm.currentFrame()
+			val := vm.stack[frame.basePointer+int(localIndex)]
+			if val == nil {
+				return vm.newError("identifier not found: %s", frame.cl.Fn.LocalNames[localIndex])
+			}
+			if err := vm.push(val); err != nil {
+				return err
+			}
+
+		case code.OpSetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip++
+
+			*vm.currentFrame().cl.Free[freeIndex].Slot = vm.pop()
+
+		case code.OpGetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip++
+
+			cl := vm.currentFrame().cl
+			val := *cl.Free[freeIndex].Slot
+			if val == nil {
+				return vm.newError("identifier not found: %s", cl.Fn.FreeNames[freeIndex])
+			}
+			if err := vm.push(val); err != nil {
+				return err
+			}
+
+		case code.OpGetBuiltin:
+			builtinIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip++
+
+			if err := vm.push(object.Builtins[builtinIndex].Builtin); err != nil {
+				return err
+			}
+
+		case code.OpFail:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			return vm.newError("%s", vm.constants[constIndex].(*object.String).Value)
+
+		case code.OpArray:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			elements := make([]object.Object, numElements)
+			copy(elements, vm.stack[vm.sp-numElements:vm.sp])
+			vm.sp = vm.sp - numElements
+
+			if err := vm.push(&object.Array{Elements: elements}); err != nil {
+				return err
+			}
+
+		case code.OpHash:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			hash, err := vm.buildHash(vm.sp-numElements, vm.sp)
+			if err != nil {
+				return err
+			}
+			vm.sp = vm.sp - numElements
+
+			if err := vm.push(hash); err != nil {
+				return err
+			}
+
+		case code.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+
+			result := object.Index(left, index)
+			if err, ok := result.(*object.Error); ok {
+				return vm.raise(err)
+			}
+			if err := vm.push(result); err != nil {
+				return err
+			}
+
+		case code.OpGetProperty:
+			name := vm.constants[code.ReadUint16(ins[ip+1:])].(*object.String)
+			vm.currentFrame().ip += 2
+
+			result := object.Property(vm.pop(), name.Value)
+			if err, ok := result.(*object.Error); ok {
+				return vm.raise(err)
+			}
+			if err := vm.push(result); err != nil {
+				return err
+			}
+
+		case code.OpSetIndex:
+			val := vm.pop()
+			index := vm.pop()
+			left := vm.pop()
+
+			result := object.SetIndex(left, index, val)
+			if err, ok := result.(*object.Error); ok {
+				return vm.raise(err)
+			}
+			if err := vm.push(result); err != nil {
+				return err
+			}
+
+		case code.OpSetProperty:
+			name := vm.constants[code.ReadUint16(ins[ip+1:])].(*object.String)
+			vm.currentFrame().ip += 2
+
+			val := vm.pop()
+			left := vm.pop()
+
+			result := object.SetProperty(left, name.Value, val)
+			if err, ok := result.(*object.Error); ok {
+				return vm.raise(err)
+			}
+			if err := vm.push(result); err != nil {
+				return err
+			}
+
+		case code.OpDup:
+			if err := vm.push(vm.stack[vm.sp-1]); err != nil {
+				return err
+			}
+
+		case code.OpDup2:
+			left, index := vm.stack[vm.sp-2], vm.stack[vm.sp-1]
+			if err := vm.push(left); err != nil {
+				return err
+			}
+			if err := vm.push(index); err != nil {
+				return err
+			}
+
+		case code.OpCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip++
+
+			if err := vm.executeCall(int(numArgs)); err != nil {
+				return err
+			}
+
+		case code.OpTailCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip++
+
+			if err := vm.executeTailCall(int(numArgs)); err != nil {
+				return err
+			}
+
+		case code.OpReturnValue:
+			returnValue := vm.pop()
+
+			if vm.framesIndex == 1 {
+				vm.result = returnValue
+				return nil
+			}
+
+			vm.returnFromFrame(returnValue)
+
+		case code.OpReturn:
+			vm.returnFromFrame(NULL)
+
+		case code.OpClosure:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			numFree := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+
+			if err := vm.pushClosure(int(constIndex), int(numFree)); err != nil {
+				return err
+			}
+
+		case code.OpCaptureLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip++
+
+			upvalue := vm.captureUpvalue(vm.currentFrame().basePointer + int(localIndex))
+			if err := vm.push(upvalue); err != nil {
+				return err
+			}
+
+		case code.OpCaptureFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip++
+
+			if err := vm.push(vm.currentFrame().cl.Free[freeIndex]); err != nil {
+				return err
+			}
+
+		case code.OpIter:
+			iterable := vm.pop()
+
+			it, ok := newIterator(iterable)
+			if !ok {
+				return vm.newError("cannot iterate over %s", iterable.Type())
+			}
+			if err := vm.push(it); err != nil {
+				return err
+			}
+
+		case code.OpIterNext:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			item, ok := vm.pop().(*iterator).next()
+			if !ok {
+				vm.currentFrame().ip = pos - 1
+				continue
+			}
+			if err, ok := item.(*object.Error); ok {
+				return vm.raise(err)
+			}
+
+			if err := vm.push(item); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (vm *VM) push(o object.Object) error {
+	if vm.sp >= len(vm.stack) {
+		vm.growStack(vm.sp + 1)
+	}
+
+	vm.stack[vm.sp] = o
+	vm.sp++
+
+	return nil
+}
+
+func (vm *VM) pop() object.Object {
+	o := vm.stack[vm.sp-1]
+	vm.sp--
+	return o
+}
+
+func (vm *VM) executeCall(numArgs int) error {
+	callee := vm.stack[vm.sp-1-numArgs]
+	switch callee := callee.(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
+	case *object.Builtin:
+		return vm.callBuiltin(callee, numArgs)
+	default:
+		return vm.newError("not a function: %s", callee.Type())
+	}
+}
+
+func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
+	if numArgs != cl.Fn.NumParameters {
+		return vm.newError("wrong number of arguments: want=%d, got=%d", cl.Fn.NumParameters, numArgs)
+	}
+	if vm.framesIndex-1 >= vm.maxDepth {
+		return vm.newError("maximum recursion depth exceeded in %s", functionName(cl.Fn.Name))
+	}
+	if err := vm.step(); err != nil {
+		return err
+	}
+
+	frame := NewFrame(cl, vm.sp-numArgs)
+	vm.enterFrame(frame, numArgs)
+
+	vm.pushFrame(frame)
+	return nil
+}
+
+// executeTailCall call a closure in place of the current frame, so that tail recursion does not use up the frames.
+// Any other callee is called as by OpCall.
+func (vm *VM) executeTailCall(numArgs int) error {
+	cl, ok := vm.stack[vm.sp-1-numArgs].(*object.Closure)
+	if !ok || vm.framesIndex == 1 {
+		return vm.executeCall(numArgs)
+	}
+	if numArgs != cl.Fn.NumParameters {
+		return vm.newError("wrong number of arguments: want=%d, got=%d", cl.Fn.NumParameters, numArgs)
+	}
+	if err := vm.step(); err != nil {
+		return err
+	}
+
+	current := vm.currentFrame()
+	site := current.cl.Fn.SourceMap.Lookup(current.ip)
+	vm.closeUpvalues(current.basePointer)
+
+	// Move the callee and the arguments over the closure and the locals of the current call
+	copy(vm.stack[current.basePointer-1:], vm.stack[vm.sp-1-numArgs:vm.sp])
+	vm.sp = current.basePointer + numArgs
+
+	// The replaced frame is discarded, so its tail calls are reused
+	frame := NewFrame(cl, current.basePointer)
+	frame.name = current.name
+	frame.tailCalls = append(current.tailCalls, object.Frame{Function: functionName(cl.Fn.Name), CallSite: site})
+	if len(frame.tailCalls) > 2*maxTailFrames {
+		frame.tailCalls = append(frame.tailCalls[:0], frame.tailCalls[len(frame.tailCalls)-maxTailFrames:]...)
+	}
+
+	vm.enterFrame(frame, numArgs)
+
+	vm.frames[vm.framesIndex-1] = frame
+	return nil
+}
+
+// enterFrame reserve the locals of frame, whose arguments are on the top of the stack
+func (vm *VM) enterFrame(frame *Frame, numArgs int) {
+	sp := frame.basePointer + frame.cl.Fn.NumLocals
+	if sp >= len(vm.stack) {
+		vm.growStack(sp + 1)
+	}
+
+	// Clear the locals, so that reading one before its let statement is an error
+	for i := frame.basePointer + numArgs; i < sp; i++ {
+		vm.stack[i] = nil
+	}
+
+	vm.sp = sp
+}
+
+func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
+	args := make([]object.Object, numArgs)
+	copy(args, vm.stack[vm.sp-numArgs:vm.sp])
+
+	result := builtin.Fn(vm.stdio, args...)
+	vm.sp = vm.sp - numArgs - 1
+
+	if err, ok := result.(*object.Error); ok {
+		return vm.raise(err)
+	}
+	if result == nil {
+		result = NULL
+	}
+
+	return vm.push(result)
+}
+
+func (vm *VM) returnFromFrame(returnValue object.Object) {
+	frame := vm.popFrame()
+	vm.closeUpvalues(frame.basePointer)
+	vm.sp = frame.basePointer - 1
+
+	vm.push(returnValue)
+}
+
+func (vm *VM) pushClosure(constIndex int, numFree int) error {
+	constant := vm.constants[constIndex]
+	function, ok := constant.(*object.CompiledFunction)
+	if !ok {
+		return fmt.Errorf("not a function: %+v", constant)
+	}
+
+	free := make([]*object.Upvalue, numFree)
+	for i := 0; i < numFree; i++ {
+		free[i] = vm.stack[vm.sp-numFree+i].(*object.Upvalue)
+	}
+	vm.sp = vm.sp - numFree
+
+	return vm.push(&object.Closure{Fn: function, Free: free})
+}
+
+// captureUpvalue return the upvalue of a stack slot, so that the closures capturing the same variable share it
+func (vm *VM) captureUpvalue(slot int) *object.Upvalue {
+	for _, open := range vm.openUpvalues {
+		if open.slot == slot {
+			return open.upvalue
+		}
+	}
+
+	upvalue := &object.Upvalue{Slot: &vm.stack[slot]}
+	vm.openUpvalues = append(vm.openUpvalues, openUpvalue{slot: slot, upvalue: upvalue})
+	return upvalue
+}
+
+// growStack make room for n values on the stack, moving the open upvalues to the new stack.
+// The stack is bounded by the frames, which are limited by maxDepth.
+func (vm *VM) growStack(n int) {
+	size := 2 * len(vm.stack)
+	for size < n {
+		size *= 2
+	}
+
+	stack := make([]object.Object, size)
+	copy(stack, vm.stack[:vm.sp])
+	vm.stack = stack
+
+	for _, open := range vm.openUpvalues {
+		open.upvalue.Slot = &vm.stack[open.slot]
+	}
+}
+
+// closeUpvalues close the upvalues of the slots from basePointer, which are about to be discarded
+func (vm *VM) closeUpvalues(basePointer int) {
+	open := vm.openUpvalues[:0]
+	for _, u := range vm.openUpvalues {
+		if u.slot >= basePointer {
+			u.upvalue.Close()
+		} else {
+			open = append(open, u)
+		}
+	}
+	vm.openUpvalues = open
+}
+
+func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, error) {
+	hashedPairs := make(map[object.HashKey]object.HashPair)
+
+	for i := startIndex; i < endIndex; i += 2 {
+		key := vm.stack[i]
+		value := vm.stack[i+1]
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return nil, vm.newError("unusable as hash key: %s", key.Type())
+		}
+
+		hashedPairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: value}
+	}
+
+	return &object.Hash{Pairs: hashedPairs}, nil
+}
+
+// newError return a runtime error raised by the current instruction
+func (vm *VM) newError(format string, a ...interface{}) error {
+	return vm.raise(&object.Error{Message: fmt.Sprintf(format, a...)})
+}
+
+// step count a loop iteration or a function call, and return an error if the execution has to stop
+func (vm *VM) step() error {
+	select {
+	case <-vm.ctx.Done():
+		return vm.raise(&object.Error{Kind: object.CancelledError, Message: "evaluation cancelled: " + vm.ctx.Err().Error()})
+	default:
+	}
+
+	vm.steps++
+	if vm.maxSteps > 0 && vm.steps > vm.maxSteps {
+		return vm.raise(&object.Error{Kind: object.BudgetExhaustedError, Message: fmt.Sprintf("step budget exhausted after %d steps", vm.maxSteps)})
+	}
+	return nil
+}
+
+// raise attach the position of the current instruction and the call stack to err
+func (vm *VM) raise(err *object.Error) error {
+	frame := vm.currentFrame()
+	if !err.Pos.IsValid() {
+		err.Pos = frame.cl.Fn.SourceMap.Lookup(frame.ip)
+	}
+
+	for i := vm.framesIndex - 1; i > 0; i-- {
+		tailCalls := vm.frames[i].tailCalls
+		for j := len(tailCalls) - 1; j >= 0; j-- {
+			err.Stack = append(err.Stack, tailCalls[j])
+		}
+
+		caller := vm.frames[i-1]
+		err.Stack = append(err.Stack, object.Frame{
+			Function: functionName(vm.frames[i].name),
+			CallSite: caller.cl.Fn.SourceMap.Lookup(caller.ip),
+		})
+	}
+
+	return err
+}
+
+// functionName return the name of a function for a stack frame
+func functionName(name string) string {
+	if name == "" {
+		return "<anonymous>"
+	}
+	return name
+}
+
+var prefixOperators = map[code.Opcode]string{
+	code.OpBang:  "!",
+	code.OpMinus: "-",
+}
+
+var infixOperators = map[code.Opcode]string{
+	code.OpAdd:          "+",
+	code.OpSub:          "-",
+	code.OpMul:          "*",
+	code.OpDiv:          "/",
+	code.OpMod:          "%",
+	code.OpEqual:        "==",
+	code.OpNotEqual:     "!=",
+	code.OpGreaterThan:  ">",
+	code.OpGreaterEqual: ">=",
+	code.OpLessThan:     "<",
+	code.OpLessEqual:    "<=",
+}
+
+// iterator is the state of a for-in loop
+type iterator struct {
+	items []object.Object
+	pos   int
+
+	lazy *object.Iterator // the items are produced by lazy instead, when it is set
+}
+
+// next return the next item, and false when there is none left
+func (it *iterator) next() (object.Object, bool) {
+	if it.lazy != nil {
+		return it.lazy.Next()
+	}
+	if it.pos >= len(it.items) {
+		return nil, false
+	}
+
+	item := it.items[it.pos]
+	it.pos++
+	return item, true
+}
+
+func newIterator(iterable object.Object) (*iterator, bool) {
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		return &iterator{items: iterable.Elements}, true
+	case *object.String:
+		items := []object.Object{}
+		for _, r := range iterable.Value {
+			items = append(items, &object.String{Value: string(r)})
+		}
+		return &iterator{items: items}, true
+	case *object.Hash:
+		return &iterator{items: iterable.Keys()}, true
+	case *object.Iterator:
+		return &iterator{lazy: iterable}, true
+	default:
+		return nil, false
+	}
+}
+
+// Inspect return "iterator"
+func (it *iterator) Inspect() string         { return "iterator" }
+func (it *iterator) Type() object.ObjectType { return "ITERATOR" }
@@ -0,0 +1,194 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NAKKA-K/learn-interpreter-in-go/compiler"
+	"github.com/NAKKA-K/learn-interpreter-in-go/lexer"
+	"github.com/NAKKA-K/learn-interpreter-in-go/object"
+	"github.com/NAKKA-K/learn-interpreter-in-go/parser"
+)
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedInspect string
+	}{
+		{"fn(x) { x }()", "ERROR: 1:1: wrong number of arguments: want=1, got=0"},
+		{"let f = fn() { 1 + f() }; f()", "ERROR: 1:20: maximum recursion depth exceeded in f"},
+		{"let f = fn(c) { if (c) { let y = 1; } y }; f(false)", "ERROR: 1:39: identifier not found: y"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		err := New(comp.Bytecode()).Run()
+		errObj, ok := err.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, err, err)
+			continue
+		}
+
+		if errObj.Inspect() != tt.expectedInspect {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedInspect, errObj.Inspect())
+		}
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	timeout, cancelTimeout := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelTimeout()
+
+	tests := []struct {
+		input    string
+		ctx      context.Context
+		maxSteps int
+		kind     object.ErrorKind
+		message  string
+	}{
+		{"while (true) { }", context.Background(), 100, object.BudgetExhaustedError, "step budget exhausted after 100 steps"},
+		{"let f = fn() { f() }; f()", context.Background(), 100, object.BudgetExhaustedError, "step budget exhausted after 100 steps"},
+		{"let f = fn() { 1 }; f()", cancelled, 0, object.CancelledError, "evaluation cancelled: context canceled"},
+		{"while (true) { }", timeout, 0, object.CancelledError, "evaluation cancelled: context deadline exceeded"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		err := New(comp.Bytecode()).RunContext(tt.ctx, tt.maxSteps)
+		errObj, ok := err.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, err, err)
+			continue
+		}
+		if errObj.Kind != tt.kind || errObj.Message != tt.message {
+			t.Errorf("wrong error for %q. expected=%d %q, got=%d %q", tt.input, tt.kind, tt.message, errObj.Kind, errObj.Message)
+		}
+	}
+}
+
+func TestSetMaxDepth(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(2)", "2"},
+		{"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(3)", "ERROR: 1:46: maximum recursion depth exceeded in f"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		machine := New(comp.Bytecode())
+		machine.SetMaxDepth(3)
+
+		var result string
+		if err := machine.Run(); err != nil {
+			result = err.(*object.Error).Inspect()
+		} else {
+			result = machine.Result().Inspect()
+		}
+		if result != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, result)
+		}
+	}
+}
+
+func TestBuiltinOutput(t *testing.T) {
+	program := parser.New(lexer.New(`puts(1); print("a", "b"); eprint("oops", 1)`)).ParseProgram()
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	machine := New(comp.Bytecode())
+	machine.SetIO(&object.IO{Stdout: &stdout, Stderr: &stderr})
+	if err := machine.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	if stdout.String() != "1\na b" || stderr.String() != "oops 1" {
+		t.Errorf("wrong output. stdout=%q, stderr=%q", stdout.String(), stderr.String())
+	}
+}
+
+func TestLines(t *testing.T) {
+	program := parser.New(lexer.New(`let n = 0; for (line in lines()) { n += len(line) }; n`)).ParseProgram()
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	machine := New(comp.Bytecode())
+	machine.SetIO(&object.IO{Stdin: strings.NewReader("ab\ncd\nef")})
+	if err := machine.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	if result := machine.Result(); result.Inspect() != "6" {
+		t.Errorf("wrong result. expected=6, got=%s", result.Inspect())
+	}
+}
+
+func TestGlobalsAcrossRuns(t *testing.T) {
+	inputs := []string{
+		"let counter = fn() { let n = 0; fn() { n += 1 } };",
+		"let c = counter(); c();",
+		"c(); c()",
+	}
+
+	constants := []object.Object{}
+	globals := make([]object.Object, GlobalsSize)
+	symbolTable := compiler.NewSymbolTable()
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+
+	var result object.Object
+	for _, input := range inputs {
+		program := parser.New(lexer.New(input)).ParseProgram()
+
+		comp := compiler.NewWithState(symbolTable, constants)
+		if err := comp.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		bytecode := comp.Bytecode()
+		constants = bytecode.Constants
+
+		machine := NewWithGlobalsStore(bytecode, globals)
+		if err := machine.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+		result = machine.Result()
+	}
+
+	integer, ok := result.(*object.Integer)
+	if !ok || integer.Value != 3 {
+		t.Errorf("wrong result. expected=3, got=%+v", result)
+	}
+}